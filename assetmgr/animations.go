@@ -0,0 +1,46 @@
+package assetmgr
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// AnimationDirections is the row order, top to bottom, of each action in a
+// directional sprite sheet (see DirectionalAnimations)
+var AnimationDirections = [4]string{"down", "up", "right", "left"}
+
+// DirectionalAnimations slices a sprite sheet laid out one row per facing
+// direction (in AnimationDirections order) into named animations. frames is
+// the sheet as returned by GetSpriteSheet, cols the number of frames per row
+// and actionRows the first row of each action. Animations are named
+// "<action>_<direction>", e.g. a sheet with idle at row 0 and walk at row 4:
+//
+//	anims, err := assetmgr.DirectionalAnimations(sprites, 6, map[string]int{"idle": 0, "walk": 4})
+//	anims["walk_up"] // the 6 frames of row 5
+func DirectionalAnimations(
+	frames []*ebiten.Image,
+	cols int,
+	actionRows map[string]int,
+) (map[string][]*ebiten.Image, error) {
+	if cols <= 0 {
+		return nil, fmt.Errorf("invalid number of columns: %d", cols)
+	}
+	rows := len(frames) / cols
+
+	anims := make(map[string][]*ebiten.Image, len(actionRows)*len(AnimationDirections))
+	for action, firstRow := range actionRows {
+		lastRow := firstRow + len(AnimationDirections) - 1
+		if firstRow < 0 || lastRow >= rows {
+			return nil, fmt.Errorf(
+				"action %s needs rows %d to %d but the sheet only has %d rows of %d frames",
+				action, firstRow, lastRow, rows, cols,
+			)
+		}
+		for i, dir := range AnimationDirections {
+			start := (firstRow + i) * cols
+			anims[action+"_"+dir] = frames[start : start+cols]
+		}
+	}
+	return anims, nil
+}
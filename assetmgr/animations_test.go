@@ -0,0 +1,51 @@
+package assetmgr
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestDirectionalAnimations(t *testing.T) {
+	// 8 rows of 6 frames, idle in rows 0-3 and walk in rows 4-7
+	frames := make([]*ebiten.Image, 8*6)
+	for i := range frames {
+		frames[i] = ebiten.NewImage(1, 1)
+	}
+	anims, err := DirectionalAnimations(frames, 6, map[string]int{"idle": 0, "walk": 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for name := range anims {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	want := []string{
+		"idle_down", "idle_left", "idle_right", "idle_up",
+		"walk_down", "walk_left", "walk_right", "walk_up",
+	}
+	if !slices.Equal(names, want) {
+		t.Errorf("animations %v, want %v", names, want)
+	}
+	for name, anim := range anims {
+		if len(anim) != 6 {
+			t.Errorf("%s has %d frames, want 6", name, len(anim))
+		}
+	}
+	// walk_up is the second walk row, row 5
+	if !slices.Equal(anims["walk_up"], frames[5*6:6*6]) {
+		t.Error("walk_up isn't the frames of row 5")
+	}
+
+	_, err = DirectionalAnimations(frames, 6, map[string]int{"attack": 6})
+	if err == nil {
+		t.Fatal("no error for an action running past the last row")
+	}
+	if !strings.Contains(err.Error(), "attack") || !strings.Contains(err.Error(), "8 rows") {
+		t.Errorf("error %q doesn't name the action and the rows the sheet has", err)
+	}
+}
@@ -27,22 +27,52 @@ import (
 	"io/fs"
 	"math"
 	"path/filepath"
+	"slices"
+	"sync"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/samredway/ebitmx"
+	"github.com/samredway/ebx/collision"
+	"github.com/samredway/ebx/geom"
 )
 
 // ----------------------------------------------------------------------------
 // Assets
 // ----------------------------------------------------------------------------
 
+// Assets holds loaded images by name. It is safe to call the Load, Add and Get
+// methods from multiple goroutines, e.g. preloading on a loading screen while
+// the scene reads what has finished so far
 type Assets struct {
+	mu      sync.RWMutex
 	imgs    map[string]*ebiten.Image
 	tiles   map[string][]*ebiten.Image
 	sprites map[string][]*ebiten.Image
+	fonts   map[string]*BitmapFont
+	sheets  []*ebiten.Image // Whole images loaded by Assets, freed by Deallocate
+	trim    bool            // See SetTrimPartialFrames
+}
+
+// SetTrimPartialFrames sets whether sheets whose size isn't a whole number of
+// frames are split by dropping the incomplete trailing column and row of
+// pixels, giving (w/frameW)*(h/frameH) frames, rather than failing the load.
+// Loads already running may use either setting
+func (a *Assets) SetTrimPartialFrames(trim bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.trim = trim
+}
+
+// trimPartialFrames reads the SetTrimPartialFrames setting
+func (a *Assets) trimPartialFrames() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.trim
 }
 
 func (a *Assets) GetImage(imgName string) (*ebiten.Image, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 	img, ok := a.imgs[imgName]
 	if !ok {
 		return nil, fmt.Errorf("no image with name %s", imgName)
@@ -50,7 +80,22 @@ func (a *Assets) GetImage(imgName string) (*ebiten.Image, error) {
 	return img, nil
 }
 
+// LoadImageFromFS loads a single image from the filesystem object passed in
+func (a *Assets) LoadImageFromFS(fsys fs.FS, name, path string) error {
+	img, err := loadEbitenImage(fsys, path)
+	if err != nil {
+		return fmt.Errorf("failed to load image %s: %w", name, err)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.imgs[name] = img
+	a.sheets = append(a.sheets, img)
+	return nil
+}
+
 func (a *Assets) AddImage(imgName string, img *ebiten.Image) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	a.imgs[imgName] = img
 }
 
@@ -63,15 +108,25 @@ func (a *Assets) LoadTileSetFromFS(fsys fs.FS, name, path string, frameW, frameH
 	if err != nil {
 		return fmt.Errorf("failed to load tileset %s: %w", name, err)
 	}
-	tiles, err := splitSheet(sheet, frameW, frameH)
+	return a.addTileSet(name, sheet, frameW, frameH)
+}
+
+// addTileSet splits an already loaded tileset image into tiles stored as name
+func (a *Assets) addTileSet(name string, sheet *ebiten.Image, frameW, frameH int) error {
+	tiles, err := splitSheet(sheet, frameW, frameH, a.trimPartialFrames())
 	if err != nil {
 		return fmt.Errorf("failed to split tileset %s: %w", name, err)
 	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	a.tiles[name] = tiles
+	a.sheets = append(a.sheets, sheet)
 	return nil
 }
 
 func (a *Assets) GetTileSet(name string) ([]*ebiten.Image, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 	tileSet, ok := a.tiles[name]
 	if !ok {
 		return nil, fmt.Errorf("no tileset with name %s", name)
@@ -85,15 +140,20 @@ func (a *Assets) LoadSpriteSheetFromFS(fsys fs.FS, name, path string, frameW, fr
 	if err != nil {
 		return fmt.Errorf("failed to load sprite sheet %s: %w", path, err)
 	}
-	sprites, err := splitSheet(sheet, frameW, frameH)
+	sprites, err := splitSheet(sheet, frameW, frameH, a.trimPartialFrames())
 	if err != nil {
 		return fmt.Errorf("failed to split sprite sheet %s: %w", path, err)
 	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	a.sprites[name] = sprites
+	a.sheets = append(a.sheets, sheet)
 	return nil
 }
 
 func (a *Assets) GetSpriteSheet(name string) ([]*ebiten.Image, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 	spriteSheet, ok := a.sprites[name]
 	if !ok {
 		return nil, fmt.Errorf("no sprite sheet with name %s", name)
@@ -101,22 +161,83 @@ func (a *Assets) GetSpriteSheet(name string) ([]*ebiten.Image, error) {
 	return spriteSheet, nil
 }
 
+// LoadBitmapFontFromFS loads a fixed width pixel font from an image with one
+// glyph per glyphW x glyphH tile. charset lists the characters of the tiles in
+// order, left to right then top to bottom, e.g. " ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+func (a *Assets) LoadBitmapFontFromFS(fsys fs.FS, name, path string, glyphW, glyphH int, charset string) error {
+	sheet, err := loadEbitenImage(fsys, path)
+	if err != nil {
+		return fmt.Errorf("failed to load bitmap font %s: %w", name, err)
+	}
+	glyphs, err := splitSheet(sheet, glyphW, glyphH, a.trimPartialFrames())
+	if err != nil {
+		return fmt.Errorf("failed to split bitmap font %s: %w", name, err)
+	}
+	font, err := newBitmapFont(glyphs, glyphW, glyphH, charset)
+	if err != nil {
+		return fmt.Errorf("failed to map bitmap font %s: %w", name, err)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.fonts[name] = font
+	a.sheets = append(a.sheets, sheet)
+	return nil
+}
+
+func (a *Assets) GetBitmapFont(name string) (*BitmapFont, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	font, ok := a.fonts[name]
+	if !ok {
+		return nil, fmt.Errorf("no bitmap font with name %s", name)
+	}
+	return font, nil
+}
+
+// Deallocate frees the GPU memory of every image Assets loaded itself and
+// forgets all stored assets. Images passed in with AddImage are owned by the
+// caller and are not freed. Call it when the assets are no longer needed,
+// e.g. from Scene.OnExit (see engine.BaseScene.Track)
+func (a *Assets) Deallocate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, sheet := range a.sheets {
+		sheet.Deallocate()
+	}
+	a.sheets = nil
+	clear(a.imgs)
+	clear(a.tiles)
+	clear(a.sprites)
+	clear(a.fonts)
+}
+
 // NewAssets is constructor for Assets
 func NewAssets() *Assets {
 	return &Assets{
 		imgs:    map[string]*ebiten.Image{},
 		tiles:   map[string][]*ebiten.Image{},
 		sprites: map[string][]*ebiten.Image{},
+		fonts:   map[string]*BitmapFont{},
 	}
 }
 
-func splitSheet(sheet *ebiten.Image, frameW, frameH int) ([]*ebiten.Image, error) {
+// splitSheet cuts a sheet into frames left to right, top to bottom. With trim
+// any partial frames at the right and bottom edges are left out, otherwise
+// they are an error
+func splitSheet(sheet *ebiten.Image, frameW, frameH int, trim bool) ([]*ebiten.Image, error) {
 	b := sheet.Bounds()
 	w := b.Dx()
 	h := b.Dy()
 
+	if frameW <= 0 || frameH <= 0 {
+		return nil, fmt.Errorf("invalid frame dimensions (%dx%d)", frameW, frameH)
+	}
 	if w%frameW != 0 || h%frameH != 0 {
-		return nil, fmt.Errorf("sheet dimensions (%dx%d) not divisible by frame dimensions (%dx%d)", w, h, frameW, frameH)
+		if !trim {
+			return nil, fmt.Errorf("sheet dimensions (%dx%d) not divisible by frame dimensions (%dx%d)", w, h, frameW, frameH)
+		}
+		w -= w % frameW
+		h -= h % frameH
 	}
 
 	var tiles []*ebiten.Image
@@ -153,19 +274,34 @@ type FirstGid int
 // TilesetInfo stores metadata about a tileset referenced in the map
 type TilesetInfo struct {
 	imgSource string // Path to the image file
+	defSource string // File the tileset is defined in, its .tsx or the .tmx if embedded
 	tileW     int    // Tile width
 	tileH     int    // Tile height
 }
 
+// ImageSource returns the tileset image path as written in its .tsx file
+func (ti TilesetInfo) ImageSource() string { return ti.imgSource }
+
+// TileSize returns the size in px of each tile in the tileset
+func (ti TilesetInfo) TileSize() geom.Size { return geom.Size{W: ti.tileW, H: ti.tileH} }
+
 // TilesetManager manages tileset metadata and tile ID resolution
 type TilesetManager struct {
 	infos  map[FirstGid]TilesetInfo // Tileset metadata keyed by firstGid
 	assets *Assets                  // Reference to assets for loading tile images
 }
 
-// Add registers a tileset with its firstGid
-func (ts *TilesetManager) Add(firstGid FirstGid, info TilesetInfo) {
+// Add registers a tileset with its firstGid. Two tilesets can't share a
+// firstGid, as which one a tile ID belongs to would be ambiguous
+func (ts *TilesetManager) Add(firstGid FirstGid, info TilesetInfo) error {
+	if existing, ok := ts.infos[firstGid]; ok {
+		return fmt.Errorf(
+			"tilesets %s and %s both have firstgid %d",
+			tilesetName(existing), tilesetName(info), firstGid,
+		)
+	}
 	ts.infos[firstGid] = info
+	return nil
 }
 
 // GetImageForTileId returns the tile image for a given global tile ID
@@ -174,20 +310,11 @@ func (ts *TilesetManager) GetImageForTileId(globalId int) (*ebiten.Image, error)
 		return nil, nil // 0 means empty tile
 	}
 
-	// Find which tileset this ID belongs to by checking firstGids in descending order
-	var matchingFirstGid FirstGid
-	for firstGid := range ts.infos {
-		if globalId >= int(firstGid) && firstGid > matchingFirstGid {
-			matchingFirstGid = firstGid
-		}
-	}
-
-	if matchingFirstGid == 0 {
-		return nil, fmt.Errorf("no tileset found for tile ID %d", globalId)
+	firstGid, info, err := ts.lookup(globalId)
+	if err != nil {
+		return nil, err
 	}
-
-	info := ts.infos[matchingFirstGid]
-	localId := globalId - int(matchingFirstGid)
+	localId := globalId - int(firstGid)
 
 	// Get the tileset by image filename
 	imgFilename := filepath.Base(info.imgSource)
@@ -203,6 +330,66 @@ func (ts *TilesetManager) GetImageForTileId(globalId int) (*ebiten.Image, error)
 	return tileSet[localId], nil
 }
 
+// TileSize returns the source size in px of the tile with the given global ID
+func (ts *TilesetManager) TileSize(globalId int) (geom.Size, error) {
+	_, info, err := ts.lookup(globalId)
+	if err != nil {
+		return geom.Size{}, err
+	}
+	return info.TileSize(), nil
+}
+
+// tilesetName describes a tileset for errors by where it is defined, falling
+// back to its image for tilesets added by hand
+func tilesetName(info TilesetInfo) string {
+	if info.defSource != "" {
+		return fmt.Sprintf("%s (%s)", info.defSource, info.imgSource)
+	}
+	return info.imgSource
+}
+
+// MaxTileSize returns the largest tile width and height across the tilesets
+func (ts *TilesetManager) MaxTileSize() geom.Size {
+	var size geom.Size
+	for _, info := range ts.infos {
+		size.W = max(size.W, info.tileW)
+		size.H = max(size.H, info.tileH)
+	}
+	return size
+}
+
+// FirstGids returns the first global tile ID of each tileset in ascending order
+func (ts *TilesetManager) FirstGids() []FirstGid {
+	gids := make([]FirstGid, 0, len(ts.infos))
+	for firstGid := range ts.infos {
+		gids = append(gids, firstGid)
+	}
+	slices.Sort(gids)
+	return gids
+}
+
+// Info returns the metadata of the tileset starting at firstGid
+func (ts *TilesetManager) Info(firstGid FirstGid) (TilesetInfo, bool) {
+	info, ok := ts.infos[firstGid]
+	return info, ok
+}
+
+// lookup finds the tileset a global tile ID belongs to
+func (ts *TilesetManager) lookup(globalId int) (FirstGid, TilesetInfo, error) {
+	// Find which tileset this ID belongs to by picking the highest firstGid <= globalId
+	var matchingFirstGid FirstGid
+	for firstGid := range ts.infos {
+		if globalId >= int(firstGid) && firstGid > matchingFirstGid {
+			matchingFirstGid = firstGid
+		}
+	}
+
+	if matchingFirstGid == 0 {
+		return 0, TilesetInfo{}, fmt.Errorf("no tileset found for tile ID %d", globalId)
+	}
+	return matchingFirstGid, ts.infos[matchingFirstGid], nil
+}
+
 // NewTilesetManager creates a new Tilesets manager
 func NewTilesetManager(assets *Assets) *TilesetManager {
 	return &TilesetManager{
@@ -217,90 +404,156 @@ func NewTilesetManager(assets *Assets) *TilesetManager {
 
 // TileMap represents a whole tilemap - world or level. Currently it is designed
 // to work by loading .tmx files (created in the free and open source Tiled level
-// editor) and has a dependendency on ebitmx
+// editor) and has a dependendency on ebitmx. Its tile data and collision
+// queries (BlocksMove, NearestFreeTile etc.) come from the embedded
+// collision.Map
 // Note that Assets.tiles[name] will load tiles in the same order as Tiled, however
 // tiled uses ids from 1 not 0 so the ids of the tiles in each layer will be the
 // same as the index + 1 in Assets.tiles
 type TileMap struct {
-	*ebitmx.EbitenMap                 // Embedded map data from ebitmx
-	tilesets          *TilesetManager // Tileset manager
+	*collision.Map                 // Map data and tile collision
+	tilesets       *TilesetManager // Tileset manager
+	tileLayers     []tmxTileLayer  // Opacity, visibility and parallax by layer index, missing = defaults
+
+	ImageLayers []ImageLayer // Image layers in draw order, see ImageLayer.Before
+	Objects     []MapObject  // Shapes from every object layer, in map order
+
+	// RenderScale scales tile images when drawn, e.g. 2 to fill a map with 32px
+	// cells using 16px source tiles. Tiles are still placed on (and collide
+	// with) the map's TileWidth x TileHeight grid. Defaults to 1, and values
+	// <= 0 are treated as 1 (see TileScale)
+	RenderScale float64
 }
 
-// NumLayers returns the number of layers in the tilemap
-func (tm *TileMap) NumLayers() int { return len(tm.Layers) }
+// TileBlock flags which sides of a tile block movement into it (see
+// collision.TileBlock)
+type TileBlock = collision.TileBlock
 
-// GetImageById returns the tile image for a given global tile ID
-func (tm *TileMap) GetImageById(globalId int) (*ebiten.Image, error) {
-	return tm.tilesets.GetImageForTileId(globalId)
-}
-
-// OverlapsTiles returns true if a position overlaps any tiles in a given layer
-// used to check collision for example
-func (tm *TileMap) OverlapsTiles(x, y, w, h float64, layer int) (bool, error) {
-	if layer < 0 || layer >= len(tm.Layers) {
-		return false, fmt.Errorf("invalid layer index: %d (map has %d layers)", layer, len(tm.Layers))
-	}
+const (
+	BlockNorth = collision.BlockNorth
+	BlockSouth = collision.BlockSouth
+	BlockEast  = collision.BlockEast
+	BlockWest  = collision.BlockWest
+	BlockAll   = collision.BlockAll
+)
 
-	tw := float64(tm.TileWidth)
-	th := float64(tm.TileHeight)
+// TileFlipFlags are the high bits Tiled sets on a global tile ID in layer data
+// to flip or rotate the tile
+const TileFlipFlags = collision.TileFlipFlags
 
-	tx0 := int(math.Floor(x / tw))
-	ty0 := int(math.Floor(y / th))
-	tx1 := int(math.Floor((x+w-1)/tw)) + 1 // exclusive Max
-	ty1 := int(math.Floor((y+h-1)/th)) + 1
+// StripFlipFlags returns a global tile ID from layer data without any Tiled
+// flip/rotation flags
+func StripFlipFlags(globalId int) int {
+	return collision.StripFlipFlags(globalId)
+}
 
-	// outside = collide with world bounds
-	if tx1 <= 0 || ty1 <= 0 || tx0 >= tm.MapWidth || ty0 >= tm.MapHeight {
-		return true, nil
-	}
-	if tx0 < 0 {
-		tx0 = 0
+// LayerVisible reports whether a tile layer is visible, as set in Tiled
+func (tm *TileMap) LayerVisible(layer int) bool {
+	if layer < 0 || layer >= len(tm.tileLayers) {
+		return true
 	}
-	if ty0 < 0 {
-		ty0 = 0
-	}
-	if tx1 > tm.MapWidth {
-		tx1 = tm.MapWidth
+	return tm.tileLayers[layer].visible
+}
+
+// LayerOpacity returns a tile layer's opacity 0-1, as set in Tiled
+func (tm *TileMap) LayerOpacity(layer int) float64 {
+	if layer < 0 || layer >= len(tm.tileLayers) {
+		return 1
 	}
-	if ty1 > tm.MapHeight {
-		ty1 = tm.MapHeight
+	return tm.tileLayers[layer].opacity
+}
+
+// LayerParallax returns a tile layer's parallax factor as set in Tiled, 1 =
+// moves with the world. It only affects drawing, collision is always tested
+// against the layer where it is in the world
+func (tm *TileMap) LayerParallax(layer int) geom.Vec2 {
+	if layer < 0 || layer >= len(tm.tileLayers) {
+		return geom.Vec2{X: 1, Y: 1}
 	}
+	return tm.tileLayers[layer].parallax
+}
 
-	rowW := tm.MapWidth
-	data := tm.Layers[layer]
-	for ty := ty0; ty < ty1; ty++ {
-		base := ty * rowW
-		for tx := tx0; tx < tx1; tx++ {
-			if data[base+tx] != 0 {
-				return true, nil
-			}
+// Tilesets returns the map's tileset manager, e.g. to list the tilesets it uses
+func (tm *TileMap) Tilesets() *TilesetManager { return tm.tilesets }
+
+// CameraRegionName marks map objects that lock the camera to their bounds (see
+// CameraRegions), either as the object's name or class or its layer's name
+const CameraRegionName = "camera_bounds"
+
+// CameraRegions returns the bounds of the map's camera regions, rectangles
+// drawn in Tiled named (or classed) "camera_bounds" or placed on an object
+// layer of that name. Pass them to camera.Camera.SetRegions for room by room
+// scrolling
+func (tm *TileMap) CameraRegions() []image.Rectangle {
+	var regions []image.Rectangle
+	for _, o := range tm.Objects {
+		if o.Name != CameraRegionName && o.Class != CameraRegionName && o.Layer != CameraRegionName {
+			continue
 		}
+		if o.Rect.W <= 0 || o.Rect.H <= 0 {
+			continue
+		}
+		regions = append(regions, image.Rect(
+			int(math.Round(o.Rect.X)),
+			int(math.Round(o.Rect.Y)),
+			int(math.Round(o.Rect.X+o.Rect.W)),
+			int(math.Round(o.Rect.Y+o.Rect.H)),
+		))
+	}
+	return regions
+}
+
+// TileScale returns the scale tile images are drawn at, RenderScale or 1 if
+// RenderScale is not positive, e.g. in a TileMap built as a struct literal
+func (tm *TileMap) TileScale() float64 {
+	if tm.RenderScale <= 0 {
+		return 1
 	}
-	return false, nil
+	return tm.RenderScale
+}
+
+// TileOrigin returns the world position to draw img, the image of the tile at
+// tx, ty, from. Like Tiled, a tile is anchored at the bottom left of its cell,
+// so tiles from a tileset with larger tiles than the map's grid (e.g. a 32px
+// tree in a 16px map) reach up and right over the neighbouring cells. A tile
+// the size of the cell sits exactly on it
+func (tm *TileMap) TileOrigin(tx, ty int, img *ebiten.Image) geom.Vec2 {
+	h := float64(img.Bounds().Dy()) * tm.TileScale()
+	return geom.Vec2{
+		X: float64(tx * tm.TileWidth),
+		Y: float64((ty+1)*tm.TileHeight) - h,
+	}
+}
+
+// TileOverhang returns how many cells, right and up, the largest tile of any
+// tileset reaches beyond its own cell (see TileOrigin), e.g. to grow a culling
+// rect so large tiles whose cell is just off screen are still drawn
+func (tm *TileMap) TileOverhang() (cols, rows int) {
+	size := tm.tilesets.MaxTileSize()
+	scale := tm.TileScale()
+	w := float64(size.W) * scale
+	h := float64(size.H) * scale
+	cols = max(0, int(math.Ceil(w/float64(tm.TileWidth)))-1)
+	rows = max(0, int(math.Ceil(h/float64(tm.TileHeight)))-1)
+	return cols, rows
+}
+
+// GetImageById returns the tile image for a given global tile ID
+func (tm *TileMap) GetImageById(globalId int) (*ebiten.Image, error) {
+	return tm.tilesets.GetImageForTileId(globalId)
 }
 
 // ForEachIn allows user to run a function (for example to render) each tile within
 // the bounds (in terms of tilesx and tilesy coords) of a rect
 func (tm *TileMap) ForEachIn(area image.Rectangle, layer int, fn func(tx, ty, id int)) error {
-	if layer < 0 || layer >= len(tm.Layers) {
-		return fmt.Errorf("invalid layer index: %d (map has %d layers)", layer, len(tm.Layers))
+	data, err := tm.LayerData(layer)
+	if err != nil {
+		return err
 	}
 
 	// clamp to map bounds
-	if area.Min.X < 0 {
-		area.Min.X = 0
-	}
-	if area.Min.Y < 0 {
-		area.Min.Y = 0
-	}
-	if area.Max.X > tm.MapWidth {
-		area.Max.X = tm.MapWidth
-	}
-	if area.Max.Y > tm.MapHeight {
-		area.Max.Y = tm.MapHeight
-	}
+	area = tm.ClampRect(area)
 
-	data := tm.Layers[layer]
 	w := tm.MapWidth
 	for ty := area.Min.Y; ty < area.Max.Y; ty++ {
 		row := ty * w
@@ -315,18 +568,37 @@ func (tm *TileMap) ForEachIn(area image.Rectangle, layer int, fn func(tx, ty, id
 	return nil
 }
 
-func (tm *TileMap) loadTilesets(fsys fs.FS, tmxDir string, tilesetRefs []ebitmx.TilesetRef) error {
+func (tm *TileMap) loadTilesets(
+	fsys fs.FS,
+	pathToTmx string,
+	tilesetRefs []ebitmx.TilesetRef,
+	embedded map[int]*ebitmx.Tileset,
+) error {
+	tmxDir := normalizeTmxDir(pathToTmx)
 	for _, tsRef := range tilesetRefs {
-		info, err := tm.loadTileset(fsys, tmxDir, tsRef)
+		var info TilesetInfo
+		var err error
+		if tsRef.Source == "" {
+			tileset, ok := embedded[tsRef.FirstGid]
+			if !ok {
+				return fmt.Errorf("tileset with firstgid %d has neither a source nor an embedded definition", tsRef.FirstGid)
+			}
+			info, err = tm.loadTileset(fsys, tmxDir, pathToTmx, tsRef.FirstGid, tileset)
+		} else {
+			info, err = tm.loadTSX(fsys, tmxDir, tsRef)
+		}
 		if err != nil {
 			return err
 		}
-		tm.tilesets.Add(FirstGid(tsRef.FirstGid), info)
+		if err := tm.tilesets.Add(FirstGid(tsRef.FirstGid), info); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (tm *TileMap) loadTileset(fsys fs.FS, tmxDir string, tsRef ebitmx.TilesetRef) (TilesetInfo, error) {
+// loadTSX loads a tileset kept in its own .tsx file
+func (tm *TileMap) loadTSX(fsys fs.FS, tmxDir string, tsRef ebitmx.TilesetRef) (TilesetInfo, error) {
 	tsxPath := resolvePath(tmxDir, tsRef.Source)
 
 	tsxBytes, err := fs.ReadFile(fsys, tsxPath)
@@ -338,23 +610,92 @@ func (tm *TileMap) loadTileset(fsys fs.FS, tmxDir string, tsRef ebitmx.TilesetRe
 	if err != nil {
 		return TilesetInfo{}, fmt.Errorf("failed to parse TSX file %s: %w", tsxPath, err)
 	}
+	return tm.loadTileset(fsys, tmxDir, tsxPath, tsRef.FirstGid, tileset)
+}
+
+// loadTileset loads the image and tile properties of a parsed tileset. srcPath
+// is the file it was defined in (its TSX, or the TMX when embedded) for errors
+func (tm *TileMap) loadTileset(
+	fsys fs.FS,
+	tmxDir, srcPath string,
+	firstGid int,
+	tileset *ebitmx.Tileset,
+) (TilesetInfo, error) {
+	for _, tile := range tileset.Tiles {
+		if block, ok := tileBlockFromProperties(tile.Properties.Properties); ok {
+			tm.SetTileBlock(firstGid+tile.Id, block)
+		}
+	}
 
 	imgPath := resolvePath(tmxDir, tileset.Image.Source)
 	imgFilename := filepath.Base(imgPath)
 
-	if err := tm.tilesets.assets.LoadTileSetFromFS(fsys, imgFilename, imgPath, tileset.TileWidth, tileset.TileHeight); err != nil {
+	sheet, err := loadEbitenImage(fsys, imgPath)
+	if err != nil {
+		return TilesetInfo{}, fmt.Errorf("failed to load tileset image %s for %s: %w", imgPath, srcPath, err)
+	}
+	if err := validateTilesetImage(srcPath, imgPath, sheet.Bounds().Size(), tileset); err != nil {
+		return TilesetInfo{}, err
+	}
+
+	if err := tm.tilesets.assets.addTileSet(imgFilename, sheet, tileset.TileWidth, tileset.TileHeight); err != nil {
 		return TilesetInfo{}, fmt.Errorf("failed to load tileset image %s: %w", imgPath, err)
 	}
 
 	return TilesetInfo{
 		imgSource: tileset.Image.Source,
+		defSource: srcPath,
 		tileW:     tileset.TileWidth,
 		tileH:     tileset.TileHeight,
 	}, nil
 }
 
+func (tm *TileMap) loadImageLayers(fsys fs.FS, tmxDir string, layers []tmxImageLayer, assets *Assets) error {
+	for _, l := range layers {
+		if l.Visible == 0 || l.Image.Source == "" {
+			continue
+		}
+		imgPath := resolvePath(tmxDir, l.Image.Source)
+		if err := assets.LoadImageFromFS(fsys, imgPath, imgPath); err != nil {
+			return err
+		}
+		img, err := assets.GetImage(imgPath)
+		if err != nil {
+			return err
+		}
+		tm.ImageLayers = append(tm.ImageLayers, ImageLayer{
+			Name:     l.Name,
+			Img:      img,
+			Offset:   geom.Vec2{X: l.OffsetX, Y: l.OffsetY},
+			Parallax: geom.Vec2{X: l.ParallaxX, Y: l.ParallaxY},
+			Opacity:  l.Opacity,
+			Before:   l.before,
+		})
+	}
+	return nil
+}
+
+// NewTileMap creates a map of width x height tiles straight from layer data
+// (global tile IDs row by row, one slice per layer) with no tilesets, so no
+// images are loaded. It is meant for generated levels, or for sharing a map
+// with code that only needs its collision. Such a map can't be drawn until
+// tilesets are added to Tilesets()
+func NewTileMap(width, height, tileW, tileH int, layers [][]int) (*TileMap, error) {
+	m, err := collision.NewMap(width, height, tileW, tileH, layers)
+	if err != nil {
+		return nil, err
+	}
+	return &TileMap{
+		Map:      m,
+		tilesets: NewTilesetManager(NewAssets()),
+
+		RenderScale: 1.0,
+	}, nil
+}
+
 // NewTileMapFromTmx loads in the level from a .tmx file (made in Tiled tile editor)
-// It automatically parses referenced .tsx files and loads all tilesets
+// It automatically parses referenced .tsx files, or tilesets embedded in the
+// .tmx, and loads all tilesets
 func NewTileMapFromTmx(fsys fs.FS, pathToTmx string, assets *Assets) (*TileMap, error) {
 	m, err := ebitmx.GetEbitenMapFromFS(fsys, pathToTmx)
 	if err != nil {
@@ -362,18 +703,88 @@ func NewTileMapFromTmx(fsys fs.FS, pathToTmx string, assets *Assets) (*TileMap,
 	}
 
 	tileMap := &TileMap{
-		EbitenMap: m,
-		tilesets:  NewTilesetManager(assets),
+		Map:      collision.NewMapFromEbitmx(m),
+		tilesets: NewTilesetManager(assets),
+
+		RenderScale: 1.0,
+	}
+
+	tmxBytes, err := fs.ReadFile(fsys, pathToTmx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TMX file %s: %w", pathToTmx, err)
+	}
+	extras, err := parseTmxExtras(bytes.NewReader(tmxBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", pathToTmx, err)
 	}
 
 	tmxDir := normalizeTmxDir(pathToTmx)
-	if err := tileMap.loadTilesets(fsys, tmxDir, m.Tilesets); err != nil {
+	if err := tileMap.loadTilesets(fsys, pathToTmx, m.Tilesets, extras.tilesets); err != nil {
 		return nil, fmt.Errorf("failed to load tilesets for %s: %w", pathToTmx, err)
 	}
+	if err := tileMap.loadImageLayers(fsys, tmxDir, extras.imageLayers, assets); err != nil {
+		return nil, fmt.Errorf("failed to load image layers for %s: %w", pathToTmx, err)
+	}
+	tileMap.Objects = extras.objects
+	tileMap.tileLayers = extras.tileLayers
 
 	return tileMap, nil
 }
 
+// validateTilesetImage checks the size of a decoded tileset image fits the
+// tile size (and the columns/tilecount) declared in its TSX, so mismatches
+// fail with an error pointing at the TSX rather than deep inside splitting the
+// sheet
+func validateTilesetImage(tsxPath, imgPath string, size image.Point, tileset *ebitmx.Tileset) error {
+	tw, th := tileset.TileWidth, tileset.TileHeight
+	if tw <= 0 || th <= 0 {
+		return fmt.Errorf("tileset %s declares an invalid tile size %dx%d", tsxPath, tw, th)
+	}
+
+	if tileset.Columns > 0 && tileset.TileCount > 0 {
+		rows := (tileset.TileCount + tileset.Columns - 1) / tileset.Columns
+		expectW, expectH := tileset.Columns*tw, rows*th
+		if size.X != expectW || size.Y != expectH {
+			return fmt.Errorf(
+				"tileset %s expects image %s to be %dx%d (%d columns, %d tiles of %dx%d) but it is %dx%d; "+
+					"the tileset likely uses margin/spacing (unsupported) or the image was resized",
+				tsxPath, imgPath, expectW, expectH, tileset.Columns, tileset.TileCount, tw, th, size.X, size.Y,
+			)
+		}
+	}
+
+	if size.X%tw != 0 || size.Y%th != 0 {
+		return fmt.Errorf(
+			"tileset %s image %s is %dx%d which is not a multiple of its %dx%d tile size; "+
+				"the tileset likely uses margin/spacing (unsupported) or the tile size is wrong",
+			tsxPath, imgPath, size.X, size.Y, tw, th,
+		)
+	}
+	return nil
+}
+
+// tileBlockFromProperties reads the blockN/S/E/W bool tile properties from a
+// tileset. ok is false if the tile sets none of them
+func tileBlockFromProperties(props []ebitmx.Property) (block TileBlock, ok bool) {
+	sides := map[string]TileBlock{
+		"blockN": BlockNorth,
+		"blockS": BlockSouth,
+		"blockE": BlockEast,
+		"blockW": BlockWest,
+	}
+	for _, p := range props {
+		side, isBlock := sides[p.Name]
+		if !isBlock {
+			continue
+		}
+		ok = true
+		if p.Value == "true" {
+			block |= side
+		}
+	}
+	return block, ok
+}
+
 func resolvePath(baseDir, path string) string {
 	if baseDir == "" {
 		return path
@@ -0,0 +1,244 @@
+package assetmgr
+
+import (
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/samredway/ebx/geom"
+	"github.com/samredway/ebx/internal/testutil"
+)
+
+func TestConcurrentLoads(t *testing.T) {
+	const sheets = 8
+	fsys := fstest.MapFS{}
+	for i := range sheets {
+		fsys[fmt.Sprintf("sheet%d.png", i)] = &fstest.MapFile{Data: testutil.PNG(64, 32, 16, 16)}
+	}
+
+	a := NewAssets()
+	var wg sync.WaitGroup
+	errs := make(chan error, sheets)
+	for i := range sheets {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs <- a.LoadSpriteSheetFromFS(fsys, fmt.Sprintf("sheet%d", i), fmt.Sprintf("sheet%d.png", i), 16, 16)
+		}()
+		// Read, and change how sheets split, while loads are still running.
+		// The sheets are whole frames so either setting loads them the same
+		go func() {
+			defer wg.Done()
+			a.GetSpriteSheet(fmt.Sprintf("sheet%d", (i+1)%sheets))
+			a.SetTrimPartialFrames(i%2 == 0)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := range sheets {
+		frames, err := a.GetSpriteSheet(fmt.Sprintf("sheet%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(frames) != 8 {
+			t.Errorf("sheet%d has %d frames, want 8", i, len(frames))
+		}
+	}
+}
+
+func TestUnsetRenderScaleDrawsAtOne(t *testing.T) {
+	tm, err := NewTileMap(2, 2, 16, 16, [][]int{{1, 1, 1, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := ebiten.NewImage(16, 32)
+
+	for _, scale := range []float64{0, -2} {
+		tm.RenderScale = scale
+		if got := tm.TileScale(); got != 1 {
+			t.Errorf("RenderScale %v: TileScale = %v, want 1", scale, got)
+		}
+		if got, want := tm.TileOrigin(1, 1, img), (geom.Vec2{X: 16, Y: 0}); got != want {
+			t.Errorf("RenderScale %v: TileOrigin = %v, want %v", scale, got, want)
+		}
+	}
+}
+
+func TestTilesetManagerDuplicateFirstGid(t *testing.T) {
+	ts := NewTilesetManager(NewAssets())
+	if err := ts.Add(1, TilesetInfo{defSource: "grass.tsx", imgSource: "grass.png"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.Add(5, TilesetInfo{defSource: "trees.tsx", imgSource: "trees.png"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ts.Add(1, TilesetInfo{defSource: "water.tsx", imgSource: "water.png"})
+	if err == nil {
+		t.Fatal("registering a duplicate firstGid didn't error")
+	}
+	for _, name := range []string{"grass.tsx", "water.tsx"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q doesn't name tileset %s", err, name)
+		}
+	}
+	if info, _ := ts.Info(1); info.defSource != "grass.tsx" {
+		t.Errorf("firstGid 1 is %s after the failed Add, want grass.tsx kept", info.defSource)
+	}
+}
+
+func TestPartialSheet(t *testing.T) {
+	// Two full rows of 16px frames and a 8px partial row, 3 frames across
+	// with a 4px partial column
+	fsys := fstest.MapFS{"sheet.png": {Data: testutil.PNG(52, 40, 16, 16)}}
+
+	strict := NewAssets()
+	if err := strict.LoadSpriteSheetFromFS(fsys, "sheet", "sheet.png", 16, 16); err == nil {
+		t.Error("strict load of a partial sheet didn't error")
+	}
+
+	lenient := NewAssets()
+	lenient.SetTrimPartialFrames(true)
+	if err := lenient.LoadSpriteSheetFromFS(fsys, "sheet", "sheet.png", 16, 16); err != nil {
+		t.Fatal(err)
+	}
+	frames, err := lenient.GetSpriteSheet("sheet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 6 {
+		t.Fatalf("lenient load gave %d frames, want 6", len(frames))
+	}
+	for i, f := range frames {
+		if b := f.Bounds(); b.Dx() != 16 || b.Dy() != 16 {
+			t.Errorf("frame %d is %dx%d, want 16x16", i, b.Dx(), b.Dy())
+		}
+	}
+	if b := frames[5].Bounds(); b.Min.X != 32 || b.Min.Y != 16 {
+		t.Errorf("last frame at %v, want (32, 16)", b.Min)
+	}
+}
+
+func TestTileMapFromFixture(t *testing.T) {
+	for _, embedded := range []bool{false, true} {
+		t.Run(fmt.Sprintf("embedded=%v", embedded), func(t *testing.T) {
+			fx := testutil.MapFixture{
+				Width: 4, Height: 2, TileW: 16, TileH: 16, Columns: 3, Rows: 2,
+				Layers:   [][]int{{1, 2, 3, 4, 5, 6, 0, 1}},
+				Embedded: embedded,
+			}
+			assets := NewAssets()
+			tm, err := NewTileMapFromTmx(fx.FS(), testutil.MapPath, assets)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tm.MapWidth != 4 || tm.MapHeight != 2 || tm.TileWidth != 16 || tm.TileHeight != 16 {
+				t.Errorf("map %dx%d of %dx%d tiles, want 4x2 of 16x16",
+					tm.MapWidth, tm.MapHeight, tm.TileWidth, tm.TileHeight)
+			}
+			if got := tm.TileIdAt(3, 1, 0); got != 1 {
+				t.Errorf("tile (3, 1) = %d, want 1", got)
+			}
+
+			// The sheet splits into exactly Columns x Rows frames
+			tiles, err := assets.GetTileSet(testutil.TilesetImagePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tiles) != 6 {
+				t.Errorf("tileset split into %d tiles, want 6", len(tiles))
+			}
+
+			// Global ID 5 is the second tile of the second row
+			img, err := tm.GetImageById(5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b := img.Bounds(); b != image.Rect(16, 16, 32, 32) {
+				t.Errorf("tile 5 is %v of the sheet, want %v", b, image.Rect(16, 16, 32, 32))
+			}
+		})
+	}
+}
+
+func TestListTilesets(t *testing.T) {
+	fx := testutil.MapFixture{
+		Width: 4, Height: 2, TileW: 16, TileH: 16, Columns: 3, Rows: 2,
+		Layers: [][]int{{1, 0, 0, 0, 0, 0, 8, 0}},
+		Extra: []testutil.Tileset{
+			{Name: "trees", FirstGid: 7, TileW: 32, TileH: 32, Columns: 2, Rows: 1},
+		},
+	}
+	tm, err := NewTileMapFromTmx(fx.FS(), testutil.MapPath, NewAssets())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := tm.Tilesets()
+	gids := ts.FirstGids()
+	if !slices.Equal(gids, []FirstGid{1, 7}) {
+		t.Fatalf("FirstGids = %v, want [1 7]", gids)
+	}
+	want := map[FirstGid]struct {
+		img  string
+		size geom.Size
+	}{
+		1: {testutil.TilesetImagePath, geom.Size{W: 16, H: 16}},
+		7: {"trees.png", geom.Size{W: 32, H: 32}},
+	}
+	for _, gid := range gids {
+		info, ok := ts.Info(gid)
+		if !ok {
+			t.Fatalf("no Info for firstGid %d", gid)
+		}
+		if info.ImageSource() != want[gid].img || info.TileSize() != want[gid].size {
+			t.Errorf("firstGid %d is %s of %v tiles, want %s of %v",
+				gid, info.ImageSource(), info.TileSize(), want[gid].img, want[gid].size)
+		}
+	}
+	if _, ok := ts.Info(2); ok {
+		t.Error("Info found a tileset at firstGid 2, which starts none")
+	}
+
+	// Global ID 8 is the second tree
+	img, err := tm.GetImageById(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b != image.Rect(32, 0, 64, 32) {
+		t.Errorf("tile 8 is %v of the trees sheet, want %v", b, image.Rect(32, 0, 64, 32))
+	}
+}
+
+func TestMisSizedTilesetImage(t *testing.T) {
+	fx := testutil.MapFixture{Width: 2, Height: 2, TileW: 16, TileH: 16, Columns: 3, Rows: 2}
+	// The tileset declares 3x2 tiles, a 48x32 image
+	for _, size := range []image.Point{{40, 32}, {64, 32}, {48, 16}} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			fsys := fx.FS()
+			fsys[testutil.TilesetImagePath].Data = testutil.PNG(size.X, size.Y, 16, 16)
+
+			_, err := NewTileMapFromTmx(fsys, testutil.MapPath, NewAssets())
+			if err == nil {
+				t.Fatal("loaded a map whose tileset image is the wrong size")
+			}
+			for _, want := range []string{testutil.TilesetPath, fmt.Sprintf("%dx%d", size.X, size.Y)} {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q doesn't mention %s", err, want)
+				}
+			}
+		})
+	}
+}
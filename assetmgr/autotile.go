@@ -0,0 +1,152 @@
+package assetmgr
+
+import (
+	"fmt"
+	"image"
+)
+
+// Neighbour bits of an auto-tiling mask. A 4 bit mask only uses the N, E, S
+// and W bits. An 8 bit mask adds the diagonals, which are only set when both
+// orthogonal neighbours beside them are also set (the usual "blob" rule that
+// brings the 256 possible masks down to 47 tile variants)
+const (
+	NeighbourN = 1 << iota
+	NeighbourNE
+	NeighbourE
+	NeighbourSE
+	NeighbourS
+	NeighbourSW
+	NeighbourW
+	NeighbourNW
+)
+
+// NeighbourOutside is the tile ID Neighbors gives for cells outside the map,
+// so they can be told apart from empty (0) cells
+const NeighbourOutside = -1
+
+// neighbourOffsets are the tx, ty offsets of the 8 neighbours clockwise from
+// north, in the same order as the Neighbour bits
+var neighbourOffsets = [8]image.Point{
+	{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1},
+}
+
+// Neighbors returns the global tile IDs around tx, ty in a layer, clockwise
+// from north: N, E, S, W, or with diagonal N, NE, E, SE, S, SW, W, NW (the
+// order of the Neighbour bits). Cells outside the map are NeighbourOutside.
+// An invalid layer gives nil
+func (tm *TileMap) Neighbors(tx, ty, layer int, diagonal bool) []int {
+	if layer < 0 || layer >= len(tm.Layers) {
+		return nil
+	}
+	ids := make([]int, 0, len(neighbourOffsets))
+	for i, off := range neighbourOffsets {
+		if !diagonal && i%2 == 1 {
+			continue
+		}
+		nx, ny := tx+off.X, ty+off.Y
+		if nx < 0 || ny < 0 || nx >= tm.MapWidth || ny >= tm.MapHeight {
+			ids = append(ids, NeighbourOutside)
+			continue
+		}
+		ids = append(ids, tm.Layers[layer][ny*tm.MapWidth+nx])
+	}
+	return ids
+}
+
+// AutoTileRule describes a terrain for TileMap.AutoTile
+type AutoTileRule struct {
+	// IsTerrain reports whether a cell holding the global tile ID is part of
+	// the terrain, e.g. any wall variant
+	IsTerrain func(globalId int) bool
+
+	// Tiles maps a neighbour mask (see NeighbourN etc.) to the global tile ID
+	// to use for a terrain cell with those terrain neighbours
+	Tiles map[int]int
+
+	Diagonals bool // Use an 8 bit mask rather than a 4 bit one
+	EdgeSolid bool // Cells outside the map count as terrain rather than empty
+
+	// Default is used for masks missing from Tiles, 0 leaves the cell as is
+	Default int
+}
+
+// AutoTile picks the tile variant for every terrain cell of a layer from its
+// terrain neighbours, e.g. so procedurally painted walls get the right edge
+// and corner tiles. Every mask is worked out from the layer as it was before
+// any cell is changed, so the order cells are visited doesn't matter
+func (tm *TileMap) AutoTile(layer int, rule AutoTileRule) error {
+	data, err := tm.LayerData(layer)
+	if err != nil {
+		return err
+	}
+	if rule.IsTerrain == nil {
+		return fmt.Errorf("auto tile rule has no IsTerrain func")
+	}
+
+	terrain := make([]bool, len(data))
+	for i, id := range data {
+		terrain[i] = id != 0 && rule.IsTerrain(StripFlipFlags(id))
+	}
+
+	isTerrain := func(tx, ty int) bool {
+		if tx < 0 || ty < 0 || tx >= tm.MapWidth || ty >= tm.MapHeight {
+			return rule.EdgeSolid
+		}
+		return terrain[ty*tm.MapWidth+tx]
+	}
+
+	for ty := range tm.MapHeight {
+		for tx := range tm.MapWidth {
+			i := ty*tm.MapWidth + tx
+			if !terrain[i] {
+				continue
+			}
+			mask := autoTileMask(tx, ty, rule.Diagonals, isTerrain)
+			if id, ok := rule.Tiles[mask]; ok {
+				data[i] = id
+			} else if rule.Default != 0 {
+				data[i] = rule.Default
+			}
+		}
+	}
+	return nil
+}
+
+// autoTileMask builds the neighbour mask of the cell at tx, ty
+func autoTileMask(tx, ty int, diagonals bool, isTerrain func(tx, ty int) bool) int {
+	n := isTerrain(tx, ty-1)
+	e := isTerrain(tx+1, ty)
+	s := isTerrain(tx, ty+1)
+	w := isTerrain(tx-1, ty)
+
+	mask := 0
+	if n {
+		mask |= NeighbourN
+	}
+	if e {
+		mask |= NeighbourE
+	}
+	if s {
+		mask |= NeighbourS
+	}
+	if w {
+		mask |= NeighbourW
+	}
+	if !diagonals {
+		return mask
+	}
+
+	if n && e && isTerrain(tx+1, ty-1) {
+		mask |= NeighbourNE
+	}
+	if s && e && isTerrain(tx+1, ty+1) {
+		mask |= NeighbourSE
+	}
+	if s && w && isTerrain(tx-1, ty+1) {
+		mask |= NeighbourSW
+	}
+	if n && w && isTerrain(tx-1, ty-1) {
+		mask |= NeighbourNW
+	}
+	return mask
+}
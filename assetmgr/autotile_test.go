@@ -0,0 +1,124 @@
+package assetmgr
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNeighbors(t *testing.T) {
+	// Each cell holds its own index + 1
+	tm, err := NewTileMap(3, 3, 16, 16, [][]int{{
+		1, 2, 3,
+		4, 5, 6,
+		7, 8, 9,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const out = NeighbourOutside
+
+	tests := []struct {
+		name     string
+		tx, ty   int
+		diagonal bool
+		want     []int // N, E, S, W or N, NE, E, SE, S, SW, W, NW
+	}{
+		{"corner", 0, 0, false, []int{out, 2, 4, out}},
+		{"corner diagonal", 0, 0, true, []int{out, out, 2, 5, 4, out, out, out}},
+		{"edge", 1, 2, false, []int{5, 9, out, 7}},
+		{"edge diagonal", 1, 2, true, []int{5, 6, 9, out, out, out, 7, 4}},
+		{"interior", 1, 1, false, []int{2, 6, 8, 4}},
+		{"interior diagonal", 1, 1, true, []int{2, 3, 6, 9, 8, 7, 4, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tm.Neighbors(tt.tx, tt.ty, 0, tt.diagonal); !slices.Equal(got, tt.want) {
+				t.Errorf("Neighbors(%d, %d) = %v, want %v", tt.tx, tt.ty, got, tt.want)
+			}
+		})
+	}
+
+	if got := tm.Neighbors(1, 1, 1, false); got != nil {
+		t.Errorf("Neighbors in an invalid layer = %v, want nil", got)
+	}
+}
+
+func TestAutoTile(t *testing.T) {
+	walls := []int{
+		1, 1, 0, 0,
+		1, 1, 1, 0,
+		0, 0, 1, 0,
+	}
+	// Every mask maps to 100 + mask so the result shows the mask of each cell
+	tiles := map[int]int{}
+	for mask := range 256 {
+		tiles[mask] = 100 + mask
+	}
+	N, NE, E, SE, S, SW, W, NW := NeighbourN, NeighbourNE, NeighbourE, NeighbourSE,
+		NeighbourS, NeighbourSW, NeighbourW, NeighbourNW
+
+	tests := []struct {
+		name      string
+		diagonals bool
+		edgeSolid bool
+		want      []int
+	}{
+		{"4 bit", false, false, []int{
+			100 + E + S, 100 + S + W, 0, 0,
+			100 + N + E, 100 + N + E + W, 100 + S + W, 0,
+			0, 0, 100 + N, 0,
+		}},
+		// Diagonals only count with both orthogonal neighbours beside them
+		{"8 bit", true, false, []int{
+			100 + E + SE + S, 100 + S + SW + W, 0, 0,
+			100 + N + NE + E, 100 + N + E + W + NW, 100 + S + W, 0,
+			0, 0, 100 + N, 0,
+		}},
+		{"edge solid", false, true, []int{
+			100 + N + E + S + W, 100 + N + S + W, 0, 0,
+			100 + N + E + W, 100 + N + E + W, 100 + S + W, 0,
+			0, 0, 100 + N + S, 0,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm, err := NewTileMap(4, 3, 16, 16, [][]int{slices.Clone(walls)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = tm.AutoTile(0, AutoTileRule{
+				IsTerrain: func(id int) bool { return id == 1 },
+				Tiles:     tiles,
+				Diagonals: tt.diagonals,
+				EdgeSolid: tt.edgeSolid,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tm.Layers[0]; !slices.Equal(got, tt.want) {
+				t.Errorf("auto tiled layer %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	tm, err := NewTileMap(4, 3, 16, 16, [][]int{slices.Clone(walls)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the lone end piece has a tile, the rest fall back to Default
+	err = tm.AutoTile(0, AutoTileRule{
+		IsTerrain: func(id int) bool { return id == 1 },
+		Tiles:     map[int]int{N: 7},
+		Default:   9,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{9, 9, 0, 0, 9, 9, 9, 0, 0, 0, 7, 0}; !slices.Equal(tm.Layers[0], want) {
+		t.Errorf("auto tiled layer %v, want missing masks set to Default %v", tm.Layers[0], want)
+	}
+
+	if err := tm.AutoTile(0, AutoTileRule{}); err == nil {
+		t.Error("no error for a rule without IsTerrain")
+	}
+}
@@ -0,0 +1,14 @@
+package assetmgr
+
+import "github.com/samredway/ebx/collision"
+
+// CollisionGrid is the one place that decides which cells of a map's collision
+// layer are solid (see collision.Grid)
+type CollisionGrid = collision.Grid
+
+// NewCollisionGrid is constructor for CollisionGrid. solid picks which tiles in
+// the layer are solid, given the global tile ID without flip flags. Pass nil
+// for every non-empty tile
+func NewCollisionGrid(tm *TileMap, layer int, solid func(globalId int) bool) (*CollisionGrid, error) {
+	return collision.NewGrid(tm.Map, layer, solid)
+}
@@ -0,0 +1,43 @@
+package assetmgr
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// BitmapFont is a fixed width pixel font cut from an image with one glyph per
+// tile (see Assets.LoadBitmapFontFromFS and ui.DrawBitmapString)
+type BitmapFont struct {
+	glyphs map[rune]*ebiten.Image
+	glyphW int
+	glyphH int
+}
+
+// Glyph returns the image for r. ok is false if the font has no glyph for it
+func (f *BitmapFont) Glyph(r rune) (img *ebiten.Image, ok bool) {
+	img, ok = f.glyphs[r]
+	return img, ok
+}
+
+// GlyphSize returns the width and height px of every glyph
+func (f *BitmapFont) GlyphSize() (w, h int) { return f.glyphW, f.glyphH }
+
+// newBitmapFont maps each rune of charset, in order, to the tile at the same
+// index in glyphs (tiles read left to right, top to bottom)
+func newBitmapFont(glyphs []*ebiten.Image, glyphW, glyphH int, charset string) (*BitmapFont, error) {
+	f := &BitmapFont{
+		glyphs: map[rune]*ebiten.Image{},
+		glyphW: glyphW,
+		glyphH: glyphH,
+	}
+	i := 0
+	for _, r := range charset {
+		if i >= len(glyphs) {
+			return nil, fmt.Errorf("charset has %d characters but the image only has %d glyphs", len([]rune(charset)), len(glyphs))
+		}
+		f.glyphs[r] = glyphs[i]
+		i++
+	}
+	return f, nil
+}
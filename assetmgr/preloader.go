@@ -0,0 +1,107 @@
+package assetmgr
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Preloader queues asset load tasks and runs them in the background so a scene
+// can keep drawing (e.g. a loading bar) while assets stream in.
+// A failing task is recorded and the rest of the batch still runs.
+//
+// Example:
+//
+//	p := assetmgr.NewPreloader()
+//	p.Add("player", func() error {
+//		return assets.LoadSpriteSheetFromFS(gameassets.GameFS, "Player", "Player_sprites.png", 48, 48)
+//	})
+//	p.Start(nil)
+//
+//	// Then in Update
+//	select {
+//	case <-p.Done():
+//		// check p.Errors() and switch scene
+//	default:
+//		loaded, total := p.Progress()
+//		// update loading bar
+//	}
+type Preloader struct {
+	mu      sync.Mutex
+	tasks   []preloadTask
+	loaded  int
+	errs    []error
+	started bool
+	done    chan struct{}
+}
+
+type preloadTask struct {
+	name string
+	load func() error
+}
+
+// Add queues a load task. name is only used to identify the task in errors.
+// Tasks must be added before Start is called
+func (p *Preloader) Add(name string, load func() error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started {
+		panic(fmt.Sprintf("Preloader: cannot add task %s after Start", name))
+	}
+	p.tasks = append(p.tasks, preloadTask{name: name, load: load})
+}
+
+// Start runs the queued tasks in order on a background goroutine. onProgress
+// may be nil, otherwise it is called from that goroutine after each task with
+// the number of tasks finished so far (failed ones included) and the total.
+// Calling Start more than once has no effect
+func (p *Preloader) Start(onProgress func(loaded, total int)) {
+	p.mu.Lock()
+	if p.started {
+		p.mu.Unlock()
+		return
+	}
+	p.started = true
+	tasks := p.tasks
+	p.mu.Unlock()
+
+	go func() {
+		defer close(p.done)
+		for _, t := range tasks {
+			err := t.load()
+
+			p.mu.Lock()
+			if err != nil {
+				p.errs = append(p.errs, fmt.Errorf("failed to preload %s: %w", t.name, err))
+			}
+			p.loaded++
+			loaded := p.loaded
+			p.mu.Unlock()
+
+			if onProgress != nil {
+				onProgress(loaded, len(tasks))
+			}
+		}
+	}()
+}
+
+// Progress returns the number of finished tasks and the total number queued
+func (p *Preloader) Progress() (loaded, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.loaded, len(p.tasks)
+}
+
+// Done returns a channel that is closed once every task has finished
+func (p *Preloader) Done() <-chan struct{} { return p.done }
+
+// Errors returns the errors of any failed tasks so far, in task order
+func (p *Preloader) Errors() []error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]error(nil), p.errs...)
+}
+
+// NewPreloader is constructor for Preloader
+func NewPreloader() *Preloader {
+	return &Preloader{done: make(chan struct{})}
+}
@@ -0,0 +1,56 @@
+package assetmgr
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPreloaderRunsToCompletion(t *testing.T) {
+	p := NewPreloader()
+	var ran []string
+	p.Add("a", func() error { ran = append(ran, "a"); return nil })
+	p.Add("b", func() error { ran = append(ran, "b"); return errors.New("missing file") })
+	p.Add("c", func() error { ran = append(ran, "c"); return nil })
+
+	var progress [][2]int
+	p.Start(func(loaded, total int) {
+		progress = append(progress, [2]int{loaded, total})
+	})
+
+	select {
+	case <-p.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("preloader never finished")
+	}
+
+	// Done is closed after the last callback, so reading here is race free
+	if want := []string{"a", "b", "c"}; !slices.Equal(ran, want) {
+		t.Errorf("tasks ran %v, want %v", ran, want)
+	}
+	if want := [][2]int{{1, 3}, {2, 3}, {3, 3}}; !slices.Equal(progress, want) {
+		t.Errorf("progress %v, want %v", progress, want)
+	}
+	if loaded, total := p.Progress(); loaded != 3 || total != 3 {
+		t.Errorf("Progress() = %d, %d, want 3, 3", loaded, total)
+	}
+
+	errs := p.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, want 1 for the failed task: %v", len(errs), errs)
+	}
+	if got := errs[0].Error(); got != "failed to preload b: missing file" {
+		t.Errorf("error = %q", got)
+	}
+}
+
+func TestPreloaderNoTasks(t *testing.T) {
+	p := NewPreloader()
+	p.Start(nil)
+	select {
+	case <-p.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("empty preloader never finished")
+	}
+}
@@ -0,0 +1,86 @@
+package assetmgr
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// RenderFullMaxSize is the largest width or height in px RenderFull will
+// create. The image is held on the GPU at 4 bytes per px, so a map at this
+// limit in both directions takes 1GB
+const RenderFullMaxSize = 16384
+
+// RenderFull draws the whole map at full size (MapWidth*TileWidth by
+// MapHeight*TileHeight) into a new image without a camera, e.g. for a level
+// select thumbnail. Visible tile layers are drawn in order with their opacity,
+// with image layers at their offset (ignoring parallax) under the tile layer
+// they precede. The caller owns the image and should Deallocate it when done.
+// Maps larger than RenderFullMaxSize in either direction return an error
+func (tm *TileMap) RenderFull() (*ebiten.Image, error) {
+	w, h := tm.MapWidth*tm.TileWidth, tm.MapHeight*tm.TileHeight
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("cannot render an empty map of %dx%d px", w, h)
+	}
+	if w > RenderFullMaxSize || h > RenderFullMaxSize {
+		return nil, fmt.Errorf(
+			"map of %dx%d px is larger than the %dpx render limit",
+			w, h, RenderFullMaxSize,
+		)
+	}
+
+	out := ebiten.NewImage(w, h)
+	opts := &ebiten.DrawImageOptions{}
+	for layer := 0; layer <= tm.NumLayers(); layer++ {
+		for _, il := range tm.ImageLayers {
+			if il.Before != layer {
+				continue
+			}
+			opts.GeoM.Reset()
+			opts.ColorScale.Reset()
+			opts.GeoM.Translate(il.Offset.X, il.Offset.Y)
+			opts.ColorScale.ScaleAlpha(float32(il.Opacity))
+			out.DrawImage(il.Img, opts)
+		}
+		if layer == tm.NumLayers() || !tm.LayerVisible(layer) {
+			continue
+		}
+		if err := tm.renderLayer(out, layer, opts); err != nil {
+			out.Deallocate()
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// renderLayer draws every tile of a layer onto dst at full size
+func (tm *TileMap) renderLayer(dst *ebiten.Image, layer int, opts *ebiten.DrawImageOptions) error {
+	alpha := float32(tm.LayerOpacity(layer))
+	scale := tm.TileScale()
+	var tileErr error
+	err := tm.ForEachIn(image.Rect(0, 0, tm.MapWidth, tm.MapHeight), layer, func(tx, ty, id int) {
+		if tileErr != nil {
+			return
+		}
+		img, err := tm.GetImageById(id)
+		if err != nil {
+			tileErr = fmt.Errorf("failed to get tile image for ID %d at (%d, %d): %w", id, tx, ty, err)
+			return
+		}
+		if img == nil {
+			return
+		}
+		origin := tm.TileOrigin(tx, ty, img)
+		opts.GeoM.Reset()
+		opts.ColorScale.Reset()
+		opts.GeoM.Scale(scale, scale)
+		opts.GeoM.Translate(origin.X, origin.Y)
+		opts.ColorScale.ScaleAlpha(alpha)
+		dst.DrawImage(img, opts)
+	})
+	if err != nil {
+		return err
+	}
+	return tileErr
+}
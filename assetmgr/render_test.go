@@ -0,0 +1,43 @@
+package assetmgr
+
+import (
+	"testing"
+
+	"github.com/samredway/ebx/internal/testutil"
+)
+
+func TestRenderFullSize(t *testing.T) {
+	fx := testutil.MapFixture{
+		Width: 5, Height: 3, TileW: 16, TileH: 16, Columns: 2, Rows: 2,
+		Layers: [][]int{
+			{1, 2, 3, 4, 1, 2, 3, 4, 1, 2, 3, 4, 1, 2, 3},
+			{0, 0, 4, 0, 0},
+		},
+		LayerAttrs: []string{``, `opacity="0.5"`},
+	}
+	tm, err := NewTileMapFromTmx(fx.FS(), testutil.MapPath, NewAssets())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := tm.RenderFull()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer img.Deallocate()
+	if b := img.Bounds(); b.Dx() != 5*16 || b.Dy() != 3*16 {
+		t.Errorf("rendered %dx%d px, want %dx%d", b.Dx(), b.Dy(), 5*16, 3*16)
+	}
+}
+
+func TestRenderFullTooLarge(t *testing.T) {
+	const width = RenderFullMaxSize/16 + 1
+	tm, err := NewTileMap(width, 1, 16, 16, [][]int{make([]int, width)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img, err := tm.RenderFull(); err == nil {
+		img.Deallocate()
+		t.Error("RenderFull of a map over the size limit didn't error")
+	}
+}
@@ -0,0 +1,186 @@
+package assetmgr
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/samredway/ebitmx"
+	"github.com/samredway/ebx/geom"
+)
+
+// ImageLayer is a Tiled image layer: a single image placed at an offset rather
+// than a grid of tiles, typically used for backgrounds
+type ImageLayer struct {
+	Name     string
+	Img      *ebiten.Image
+	Offset   geom.Vec2 // Position of the image's top left corner in world px
+	Parallax geom.Vec2 // Scroll factor relative to the camera, 1 = moves with the world
+	Opacity  float64
+	Before   int // Index of the tile layer this is drawn under (NumLayers() = above all)
+}
+
+// MapObject is a shape from a Tiled object layer, e.g. a spawn point or a
+// trigger area. Only the position and size are kept, so ellipses and polygons
+// are reduced to their bounding box and points have a zero size
+type MapObject struct {
+	Name  string
+	Class string    // Tiled's class (called type before Tiled 1.9)
+	Layer string    // Name of the object layer it is on
+	Rect  geom.Rect // Bounds in world px
+}
+
+// tmxExtras holds the parts of a TMX file ebitmx does not parse
+type tmxExtras struct {
+	imageLayers []tmxImageLayer
+	tileLayers  []tmxTileLayer
+	objects     []MapObject
+	tilesets    map[int]*ebitmx.Tileset // Embedded tilesets by firstgid
+}
+
+// tmxTileset is a tileset element of a TMX, either a reference to a .tsx file
+// or, without a source, the tileset itself embedded in the map
+type tmxTileset struct {
+	FirstGid int    `xml:"firstgid,attr"`
+	Source   string `xml:"source,attr"`
+	ebitmx.Tileset
+}
+
+// tmxTileLayer holds the tile layer attributes ebitmx drops
+type tmxTileLayer struct {
+	opacity  float64
+	visible  bool
+	parallax geom.Vec2
+}
+
+type tmxObjectGroup struct {
+	Name    string `xml:"name,attr"`
+	Objects []struct {
+		Name   string  `xml:"name,attr"`
+		Class  string  `xml:"class,attr"`
+		Type   string  `xml:"type,attr"`
+		X      float64 `xml:"x,attr"`
+		Y      float64 `xml:"y,attr"`
+		Width  float64 `xml:"width,attr"`
+		Height float64 `xml:"height,attr"`
+	} `xml:"object"`
+}
+
+type tmxImageLayer struct {
+	Name      string  `xml:"name,attr"`
+	OffsetX   float64 `xml:"offsetx,attr"`
+	OffsetY   float64 `xml:"offsety,attr"`
+	ParallaxX float64 `xml:"parallaxx,attr"`
+	ParallaxY float64 `xml:"parallaxy,attr"`
+	Opacity   float64 `xml:"opacity,attr"`
+	Visible   int     `xml:"visible,attr"`
+	Image     struct {
+		Source string `xml:"source,attr"`
+	} `xml:"image"`
+	before int // number of tile layers preceding this one
+}
+
+// parseTmxExtras walks the top level elements of a TMX map in document order,
+// so extra layers keep their position relative to the tile layers
+func parseTmxExtras(r io.Reader) (*tmxExtras, error) {
+	extras := &tmxExtras{tilesets: map[int]*ebitmx.Tileset{}}
+	dec := xml.NewDecoder(r)
+	depth := 0
+	tileLayers := 0
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return extras, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse TMX: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			// Only look at direct children of <map>
+			if depth != 1 {
+				depth++
+				continue
+			}
+			switch t.Name.Local {
+			case "layer":
+				tileLayers++
+				depth++
+				tl, err := parseTileLayerAttrs(t.Attr)
+				if err != nil {
+					return nil, err
+				}
+				extras.tileLayers = append(extras.tileLayers, tl)
+			case "imagelayer":
+				// Tiled leaves out attributes that are at their default
+				il := tmxImageLayer{ParallaxX: 1, ParallaxY: 1, Opacity: 1, Visible: 1}
+				if err := dec.DecodeElement(&il, &t); err != nil {
+					return nil, fmt.Errorf("failed to parse image layer: %w", err)
+				}
+				il.before = tileLayers
+				extras.imageLayers = append(extras.imageLayers, il)
+			case "tileset":
+				var ts tmxTileset
+				if err := dec.DecodeElement(&ts, &t); err != nil {
+					return nil, fmt.Errorf("failed to parse tileset: %w", err)
+				}
+				if ts.Source == "" {
+					extras.tilesets[ts.FirstGid] = &ts.Tileset
+				}
+			case "objectgroup":
+				var og tmxObjectGroup
+				if err := dec.DecodeElement(&og, &t); err != nil {
+					return nil, fmt.Errorf("failed to parse object layer: %w", err)
+				}
+				for _, o := range og.Objects {
+					class := o.Class
+					if class == "" {
+						class = o.Type
+					}
+					extras.objects = append(extras.objects, MapObject{
+						Name:  o.Name,
+						Class: class,
+						Layer: og.Name,
+						Rect:  geom.Rect{X: o.X, Y: o.Y, W: o.Width, H: o.Height},
+					})
+				}
+			default:
+				depth++
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// parseTileLayerAttrs reads a tile layer's opacity, visibility and parallax,
+// which Tiled leaves out when they are at their defaults of 1, visible and 1
+func parseTileLayerAttrs(attrs []xml.Attr) (tmxTileLayer, error) {
+	tl := tmxTileLayer{opacity: 1, visible: true, parallax: geom.Vec2{X: 1, Y: 1}}
+	for _, a := range attrs {
+		var dst *float64
+		switch a.Name.Local {
+		case "opacity":
+			dst = &tl.opacity
+		case "parallaxx":
+			dst = &tl.parallax.X
+		case "parallaxy":
+			dst = &tl.parallax.Y
+		case "visible":
+			tl.visible = a.Value != "0"
+		}
+		if dst == nil {
+			continue
+		}
+		v, err := strconv.ParseFloat(a.Value, 64)
+		if err != nil {
+			return tl, fmt.Errorf("failed to parse tile layer %s %q: %w", a.Name.Local, a.Value, err)
+		}
+		*dst = v
+	}
+	return tl, nil
+}
@@ -2,10 +2,20 @@ package camera
 
 import (
 	"image"
+	"math"
 
 	"github.com/samredway/ebx/geom"
 )
 
+// cameraPan is an in progress pan from one centre point to a new target
+type cameraPan struct {
+	active   bool
+	from     geom.Vec2
+	elapsed  float64
+	duration float64
+	bounds   image.Rectangle // Region being left, allowed as well while panning
+}
+
 // Camera is a simple cam with functionality to translate wolrd coords to
 // viewport coords
 type Camera struct {
@@ -13,11 +23,43 @@ type Camera struct {
 	viewport  geom.Size       // viewport size px
 	bounds    image.Rectangle // Bounding box of whole world px
 	Zoom      float64         // Zoom level (1.0 = normal, 2.0 = 2x zoom, etc.)
+
+	LookAhead      float64   // Distance px to lead the target by in Follow (0 = off)
+	LookAheadSpeed float64   // How quickly the lead eases in/out, per second (0 = instant)
+	lead           geom.Vec2 // Current lead offset
+
+	// Aim is an extra world space offset added to the Follow target, e.g.
+	// towards the cursor in a twin stick game. Together with the look-ahead it
+	// is clamped so the target stays at least AimMargin px inside the view
+	Aim       geom.Vec2
+	AimMargin float64
+
+	pan  cameraPan // Active TransitionTo pan
+	free bool      // Follow is ignored, see SetFree
+
+	regions   []image.Rectangle // See SetRegions
+	region    int               // Index of the region holding the target, -1 = none
+	following bool              // Follow has run, so region changes pan
+
+	// RegionPan is how many seconds the camera takes to pan into a new region
+	// when the target crosses into it (0 = cut). Defaults to 0.5
+	RegionPan float64
+
+	// PixelSnap rounds screen positions from Apply to whole pixels, stopping
+	// pixel art shimmering at tile seams. Leave off for smooth sub-pixel motion
+	PixelSnap bool
 }
 
 // Viewport returns the viewport size
 func (c *Camera) Viewport() geom.Size { return c.viewport }
 
+// SetViewport updates the viewport size, for example when the window is
+// resized, and keeps the camera inside world bounds
+func (c *Camera) SetViewport(viewport geom.Size) {
+	c.viewport = viewport
+	c.clamp()
+}
+
 // CenterOn centres the camera on the given position
 func (c *Camera) CentreOn(pos geom.Vec2) {
 	c.X = pos.X - (float64(c.viewport.W) / c.Zoom / 2)
@@ -25,33 +67,254 @@ func (c *Camera) CentreOn(pos geom.Vec2) {
 	c.clamp()
 }
 
+// Centre returns the world position at the centre of the view
+func (c *Camera) Centre() geom.Vec2 {
+	return geom.Vec2{
+		X: c.X + float64(c.viewport.W)/c.Zoom/2,
+		Y: c.Y + float64(c.viewport.H)/c.Zoom/2,
+	}
+}
+
+// TransitionTo starts a smooth pan from the current view to whatever Follow is
+// next given as its target, taking duration seconds before following normally
+// again. Use it when switching the followed entity (see
+// engine.RenderSystem.SetCameraTarget). The game keeps running during the pan
+func (c *Camera) TransitionTo(duration float64) {
+	if duration <= 0 {
+		c.pan = cameraPan{}
+		return
+	}
+	c.pan = cameraPan{active: true, from: c.Centre(), duration: duration}
+}
+
+// Transitioning reports whether a TransitionTo pan is in progress
+func (c *Camera) Transitioning() bool { return c.pan.active }
+
+// SetRegions sets areas of the world, e.g. rooms, that the camera is locked
+// inside while the Follow target is in them, Zelda style. Outside every region
+// the camera is only kept inside world bounds. When the target crosses into
+// another region the camera pans over RegionPan seconds. See
+// assetmgr.TileMap.CameraRegions for loading them from a map
+func (c *Camera) SetRegions(regions []image.Rectangle) {
+	c.regions = regions
+	c.region = -1
+	c.clamp()
+}
+
+// SetFree detaches the camera from Follow so it can be moved with Pan and
+// SetZoom instead, e.g. a debug camera for looking around a level. Turning it
+// off again resumes following, centred straight back on the target
+func (c *Camera) SetFree(free bool) {
+	c.free = free
+	if !free {
+		c.lead = geom.Vec2{}
+		c.pan = cameraPan{}
+	}
+}
+
+// Free reports whether the camera is detached from Follow (see SetFree)
+func (c *Camera) Free() bool { return c.free }
+
+// Pan moves the camera by dx, dy world px, kept inside world bounds
+func (c *Camera) Pan(dx, dy float64) {
+	c.X += dx
+	c.Y += dy
+	c.clamp()
+}
+
+// SetZoom changes the zoom level keeping the same point at the centre of the
+// view, then keeps the camera inside world bounds. zoom must be > 0
+func (c *Camera) SetZoom(zoom float64) {
+	if zoom <= 0 {
+		return
+	}
+	centre := c.Centre()
+	c.Zoom = zoom
+	c.CentreOn(centre)
+}
+
+// Follow centres on target like CentreOn but leads it by LookAhead px in the
+// direction dir (e.g. the target's velocity or facing, zero when standing
+// still), plus any Aim offset. The lead eases towards that offset so starting
+// and stopping don't snap the camera. Does nothing while the camera is free
+// or if target is NaN/Inf
+func (c *Camera) Follow(target, dir geom.Vec2, dt float64) {
+	if c.free || !geom.Finite(target) {
+		return
+	}
+
+	goal := geom.Normalize(dir)
+	goal.X *= c.LookAhead
+	goal.Y *= c.LookAhead
+
+	t := 1.0
+	if c.LookAheadSpeed > 0 {
+		t = math.Min(1, c.LookAheadSpeed*dt)
+	}
+	c.lead.X += (goal.X - c.lead.X) * t
+	c.lead.Y += (goal.Y - c.lead.Y) * t
+
+	offset := c.clampOffset(geom.Vec2{X: c.lead.X + c.Aim.X, Y: c.lead.Y + c.Aim.Y})
+	centre := geom.Vec2{X: target.X + offset.X, Y: target.Y + offset.Y}
+
+	if region := c.regionAt(target); region != c.region {
+		leaving := c.limits()
+		c.region = region
+		if c.following {
+			c.TransitionTo(c.RegionPan)
+			c.pan.bounds = leaving
+		}
+	}
+	c.following = true
+
+	// Pan to where the camera will settle in the region, not the raw target,
+	// so the pan ends without a jump
+	centre = c.clampCentre(centre, c.activeBounds())
+	if c.pan.active {
+		centre = c.panTowards(centre, dt)
+	}
+	c.CentreOn(centre)
+}
+
+// clampOffset limits an offset from the follow target so the target stays at
+// least AimMargin px inside the view
+func (c *Camera) clampOffset(offset geom.Vec2) geom.Vec2 {
+	maxX := math.Max(0, float64(c.viewport.W)/c.Zoom/2-c.AimMargin)
+	maxY := math.Max(0, float64(c.viewport.H)/c.Zoom/2-c.AimMargin)
+	return geom.Vec2{
+		X: math.Max(-maxX, math.Min(maxX, offset.X)),
+		Y: math.Max(-maxY, math.Min(maxY, offset.Y)),
+	}
+}
+
+// panTowards advances a TransitionTo pan and returns the eased point between
+// where the pan started and the (possibly moving) destination
+func (c *Camera) panTowards(dest geom.Vec2, dt float64) geom.Vec2 {
+	c.pan.elapsed += dt
+	t := math.Min(1, c.pan.elapsed/c.pan.duration)
+	if t >= 1 {
+		c.pan.active = false
+		return dest
+	}
+	t = t * t * (3 - 2*t) // smoothstep ease in/out
+	return geom.Vec2{
+		X: c.pan.from.X + (dest.X-c.pan.from.X)*t,
+		Y: c.pan.from.Y + (dest.Y-c.pan.from.Y)*t,
+	}
+}
+
 // Apply calculates a screen position from a world position
 func (c *Camera) Apply(pos geom.Vec2) geom.Vec2 {
-	return geom.Vec2{X: (pos.X - c.X) * c.Zoom, Y: (pos.Y - c.Y) * c.Zoom}
+	screen := geom.Vec2{X: (pos.X - c.X) * c.Zoom, Y: (pos.Y - c.Y) * c.Zoom}
+	if c.PixelSnap {
+		screen.X = math.Round(screen.X)
+		screen.Y = math.Round(screen.Y)
+	}
+	return screen
+}
+
+// Unapply calculates a world position from a screen position (e.g. the cursor)
+func (c *Camera) Unapply(pos geom.Vec2) geom.Vec2 {
+	return geom.Vec2{X: pos.X/c.Zoom + c.X, Y: pos.Y/c.Zoom + c.Y}
+}
+
+// IsVisible reports whether any of a rect in world coords is on screen, taking
+// zoom and the rect's size into account. This is the same test the renderer
+// uses to cull sprites, so gameplay code can use it to skip off screen effects
+func (c *Camera) IsVisible(rect geom.Rect) bool {
+	screen := c.Apply(geom.Vec2{X: rect.X, Y: rect.Y})
+	w := rect.W * c.Zoom
+	h := rect.H * c.Zoom
+	return screen.X >= -w && screen.X <= float64(c.viewport.W) &&
+		screen.Y >= -h && screen.Y <= float64(c.viewport.H)
 }
 
-// clamp keeps the camera inside world bounds
+// regionAt returns the index of the first region containing p, or -1
+func (c *Camera) regionAt(p geom.Vec2) int {
+	pt := image.Pt(int(math.Floor(p.X)), int(math.Floor(p.Y)))
+	for i, r := range c.regions {
+		if pt.In(r) {
+			return i
+		}
+	}
+	return -1
+}
+
+// activeBounds returns the bounds of the current region, or the world bounds
+// when the target isn't in one
+func (c *Camera) activeBounds() image.Rectangle {
+	if c.region >= 0 && c.region < len(c.regions) {
+		return c.regions[c.region]
+	}
+	return c.bounds
+}
+
+// limits returns the bounds the camera is currently kept inside, which while
+// panning between regions also includes the region being left
+func (c *Camera) limits() image.Rectangle {
+	b := c.activeBounds()
+	if c.pan.active && !c.pan.bounds.Empty() {
+		b = b.Union(c.pan.bounds)
+	}
+	return b
+}
+
+// clamp keeps the camera inside world bounds, or the current region
 func (c *Camera) clamp() {
-	maxX := float64(c.bounds.Max.X) - float64(c.viewport.W)/c.Zoom
-	maxY := float64(c.bounds.Max.Y) - float64(c.viewport.H)/c.Zoom
+	c.Vec2 = c.clampTopLeft(c.Vec2, c.limits())
+}
+
+// clampCentre returns the nearest view centre to centre that keeps the view
+// inside bounds
+func (c *Camera) clampCentre(centre geom.Vec2, bounds image.Rectangle) geom.Vec2 {
+	halfW := float64(c.viewport.W) / c.Zoom / 2
+	halfH := float64(c.viewport.H) / c.Zoom / 2
+	topLeft := c.clampTopLeft(geom.Vec2{X: centre.X - halfW, Y: centre.Y - halfH}, bounds)
+	return geom.Vec2{X: topLeft.X + halfW, Y: topLeft.Y + halfH}
+}
+
+// clampTopLeft keeps a view with its top left corner at pos inside bounds
+func (c *Camera) clampTopLeft(pos geom.Vec2, bounds image.Rectangle) geom.Vec2 {
+	maxX := float64(bounds.Max.X) - float64(c.viewport.W)/c.Zoom
+	maxY := float64(bounds.Max.Y) - float64(c.viewport.H)/c.Zoom
 
-	if c.X < float64(c.bounds.Min.X) {
-		c.X = float64(c.bounds.Min.X)
+	if pos.X < float64(bounds.Min.X) {
+		pos.X = float64(bounds.Min.X)
 	}
-	if c.X > maxX {
-		c.X = maxX
+	if pos.X > maxX {
+		pos.X = maxX
 	}
-	if c.Y < float64(c.bounds.Min.Y) {
-		c.Y = float64(c.bounds.Min.Y)
+	if pos.Y < float64(bounds.Min.Y) {
+		pos.Y = float64(bounds.Min.Y)
 	}
-	if c.Y > maxY {
-		c.Y = maxY
+	if pos.Y > maxY {
+		pos.Y = maxY
 	}
+	return pos
 }
 
-// NewCamera creates a new camera at 0,0 that can be set to a position later
-// when CenterOn gets called
+// NewCamera creates a new camera at 0,0 (kept inside bounds) that can be set
+// to a position later when CenterOn gets called
 func NewCamera(viewport geom.Size, bounds image.Rectangle) *Camera {
 	pos := geom.Vec2{X: 0.0, Y: 0.0}
-	return &Camera{Vec2: pos, viewport: viewport, bounds: bounds, Zoom: 1.0}
+	c := &Camera{
+		Vec2:           pos,
+		viewport:       viewport,
+		bounds:         bounds,
+		Zoom:           1.0,
+		LookAheadSpeed: 5.0,
+		region:         -1,
+		RegionPan:      0.5,
+	}
+	c.clamp()
+	return c
+}
+
+// NewCameraAt creates a new camera centred on centre, kept inside bounds, e.g.
+// to frame a scene before the player spawns without a jump from 0,0 on the
+// first frame. The first Follow still centres straight on its target
+func NewCameraAt(viewport geom.Size, bounds image.Rectangle, centre geom.Vec2) *Camera {
+	c := NewCamera(viewport, bounds)
+	c.CentreOn(centre)
+	return c
 }
@@ -0,0 +1,194 @@
+package camera
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/samredway/ebx/geom"
+)
+
+// near reports whether a and b are within float error of each other
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestTransitionToSmoothstep(t *testing.T) {
+	c := NewCameraAt(geom.Size{W: 100, H: 100}, image.Rect(0, 0, 1000, 1000), geom.Vec2{X: 200, Y: 200})
+	c.Follow(geom.Vec2{X: 200, Y: 200}, geom.Vec2{}, 1.0/60)
+
+	// Pan 400px right to a new target over 1s
+	c.TransitionTo(1)
+	target := geom.Vec2{X: 600, Y: 200}
+	for i := 1; i <= 3; i++ {
+		c.Follow(target, geom.Vec2{}, 0.25)
+		tt := float64(i) * 0.25
+		want := 200 + 400*tt*tt*(3-2*tt)
+		if got := c.Centre(); !near(got.X, want) || !near(got.Y, 200) {
+			t.Errorf("%vs in centre is %v, want (%v, 200)", tt, got, want)
+		}
+		if !c.Transitioning() {
+			t.Errorf("pan over %vs into a 1s pan", tt)
+		}
+	}
+
+	c.Follow(target, geom.Vec2{}, 0.25)
+	if got := c.Centre(); got != target || c.Transitioning() {
+		t.Errorf("at the end centre is %v, transitioning %v, want %v and done", got, c.Transitioning(), target)
+	}
+}
+
+func TestApplyPixelSnap(t *testing.T) {
+	c := NewCamera(geom.Size{W: 100, H: 100}, image.Rect(0, 0, 1000, 1000))
+	c.X, c.Y = 10.25, 20.6
+	pos := geom.Vec2{X: 50, Y: 50}
+
+	for _, zoom := range []float64{1, 2} {
+		c.Zoom = zoom
+		c.PixelSnap = false
+		smooth := geom.Vec2{X: (50 - 10.25) * zoom, Y: (50 - 20.6) * zoom}
+		if got := c.Apply(pos); !near(got.X, smooth.X) || !near(got.Y, smooth.Y) {
+			t.Errorf("zoom %v unsnapped Apply = %v, want %v", zoom, got, smooth)
+		}
+
+		c.PixelSnap = true
+		want := geom.Vec2{X: math.Round(smooth.X), Y: math.Round(smooth.Y)}
+		if got := c.Apply(pos); got != want {
+			t.Errorf("zoom %v snapped Apply = %v, want %v", zoom, got, want)
+		}
+	}
+}
+
+func TestIsVisibleZoomed(t *testing.T) {
+	// At 2x zoom the 100px viewport shows world 100-150 on both axes
+	c := NewCamera(geom.Size{W: 100, H: 100}, image.Rect(0, 0, 1000, 1000))
+	c.SetZoom(2)
+	c.X, c.Y = 100, 100
+
+	tests := []struct {
+		name string
+		rect geom.Rect
+		want bool
+	}{
+		{"fully on screen", geom.Rect{X: 110, Y: 110, W: 10, H: 10}, true},
+		{"partly off the left", geom.Rect{X: 95, Y: 120, W: 10, H: 10}, true},
+		{"partly off the bottom", geom.Rect{X: 120, Y: 145, W: 10, H: 10}, true},
+		{"off the right", geom.Rect{X: 160, Y: 110, W: 10, H: 10}, false},
+		{"off the top", geom.Rect{X: 110, Y: 80, W: 10, H: 10}, false},
+		// On screen at 1x zoom but not at 2x
+		{"only visible unzoomed", geom.Rect{X: 170, Y: 170, W: 10, H: 10}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.IsVisible(tt.rect); got != tt.want {
+				t.Errorf("IsVisible(%v) = %v, want %v", tt.rect, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAimClampedInsideMargin(t *testing.T) {
+	c := NewCamera(geom.Size{W: 100, H: 100}, image.Rect(0, 0, 1000, 1000))
+	c.AimMargin = 10
+	target := geom.Vec2{X: 500, Y: 500}
+
+	// A small aim is followed as is
+	c.Aim = geom.Vec2{X: 15, Y: -20}
+	c.Follow(target, geom.Vec2{}, 1.0/60)
+	if got, want := c.Centre(), (geom.Vec2{X: 515, Y: 480}); got != want {
+		t.Errorf("centre with a small aim is %v, want %v", got, want)
+	}
+
+	// A large one is clamped so the target is AimMargin px inside the view
+	c.Aim = geom.Vec2{X: 400, Y: -1000}
+	c.Follow(target, geom.Vec2{}, 1.0/60)
+	if got := target.X - c.X; !near(got, c.AimMargin) {
+		t.Errorf("target %vpx from the left of the view, want %v", got, c.AimMargin)
+	}
+	if got := c.Y + 100 - target.Y; !near(got, c.AimMargin) {
+		t.Errorf("target %vpx from the bottom of the view, want %v", got, c.AimMargin)
+	}
+}
+
+func TestFreeCameraPan(t *testing.T) {
+	c := NewCameraAt(geom.Size{W: 100, H: 100}, image.Rect(0, 0, 400, 400), geom.Vec2{X: 200, Y: 200})
+	c.SetFree(true)
+
+	// Follow is ignored while free
+	c.Follow(geom.Vec2{X: 300, Y: 300}, geom.Vec2{}, 1.0/60)
+	if got, want := c.Vec2, (geom.Vec2{X: 150, Y: 150}); got != want {
+		t.Fatalf("free camera moved to %v by Follow, want it kept at %v", got, want)
+	}
+
+	c.Pan(30, -10)
+	if got, want := c.Vec2, (geom.Vec2{X: 180, Y: 140}); got != want {
+		t.Errorf("after Pan(30, -10) at %v, want %v", got, want)
+	}
+
+	// Kept inside the 400x400 world
+	c.Pan(1000, -1000)
+	if got, want := c.Vec2, (geom.Vec2{X: 300, Y: 0}); got != want {
+		t.Errorf("after a large Pan at %v, want clamped to %v", got, want)
+	}
+
+	c.SetFree(false)
+	c.Follow(geom.Vec2{X: 200, Y: 250}, geom.Vec2{}, 1.0/60)
+	if got, want := c.Centre(), (geom.Vec2{X: 200, Y: 250}); got != want {
+		t.Errorf("after SetFree(false) centred on %v, want back on the target %v", got, want)
+	}
+}
+
+func TestRegionsClampAndPan(t *testing.T) {
+	// Two 200x100 rooms side by side
+	c := NewCamera(geom.Size{W: 100, H: 100}, image.Rect(0, 0, 400, 100))
+	c.SetRegions([]image.Rectangle{image.Rect(0, 0, 200, 100), image.Rect(200, 0, 400, 100)})
+
+	// Near the right of the first room the view stops at the room's edge,
+	// where world bounds alone would centre on the target
+	c.Follow(geom.Vec2{X: 190, Y: 50}, geom.Vec2{}, 1.0/60)
+	if c.X != 100 || c.Transitioning() {
+		t.Fatalf("camera X %v transitioning %v in the first room, want clamped to 100 and no pan", c.X, c.Transitioning())
+	}
+
+	// Crossing into the second room pans over RegionPan to its left edge
+	target := geom.Vec2{X: 210, Y: 50}
+	c.Follow(target, geom.Vec2{}, c.RegionPan/2)
+	if !c.Transitioning() || !near(c.X, 150) {
+		t.Errorf("half way through the pan camera X %v transitioning %v, want 150 and panning", c.X, c.Transitioning())
+	}
+	c.Follow(target, geom.Vec2{}, c.RegionPan/2)
+	if c.Transitioning() || c.X != 200 {
+		t.Errorf("after the pan camera X %v transitioning %v, want 200 and done", c.X, c.Transitioning())
+	}
+
+	// Held inside the second room
+	c.Follow(geom.Vec2{X: 390, Y: 50}, geom.Vec2{}, 1.0/60)
+	if c.X != 300 {
+		t.Errorf("camera X %v at the far end of the second room, want 300", c.X)
+	}
+}
+
+func TestNewCameraClampsStart(t *testing.T) {
+	bounds := image.Rect(100, 50, 500, 350)
+	view := geom.Size{W: 100, H: 100}
+
+	if got, want := NewCamera(view, bounds).Vec2, (geom.Vec2{X: 100, Y: 50}); got != want {
+		t.Errorf("NewCamera at %v, want clamped into bounds at %v", got, want)
+	}
+
+	tests := []struct {
+		name   string
+		centre geom.Vec2
+		want   geom.Vec2 // Top left
+	}{
+		{"inside", geom.Vec2{X: 300, Y: 200}, geom.Vec2{X: 250, Y: 150}},
+		{"above left", geom.Vec2{X: -500, Y: -500}, geom.Vec2{X: 100, Y: 50}},
+		{"below right", geom.Vec2{X: 2000, Y: 2000}, geom.Vec2{X: 400, Y: 250}},
+		{"near an edge", geom.Vec2{X: 120, Y: 200}, geom.Vec2{X: 100, Y: 150}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewCameraAt(view, bounds, tt.centre).Vec2; got != tt.want {
+				t.Errorf("NewCameraAt(%v) at %v, want %v", tt.centre, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package collections
 
+import "maps"
+
 // Set hash set allows O(1) check for membership
 type Set[T comparable] map[T]struct{}
 
@@ -9,3 +11,14 @@ func (s Set[T]) Has(v T) bool      { _, ok := s[v]; return ok }
 func (s Set[T]) Clear()            { clear(s) }
 func (s Set[T]) Len() int          { return len(s) }
 func NewSet[T comparable]() Set[T] { return make(Set[T]) }
+
+// Clone returns a new set with the same members, so changing one doesn't
+// change the other
+func (s Set[T]) Clone() Set[T] {
+	c := make(Set[T], len(s))
+	maps.Copy(c, s)
+	return c
+}
+
+// Merge adds every member of other to s
+func (s Set[T]) Merge(other Set[T]) { maps.Copy(s, other) }
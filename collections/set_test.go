@@ -0,0 +1,49 @@
+package collections
+
+import "testing"
+
+func TestSetCloneIndependent(t *testing.T) {
+	s := NewSet[int]()
+	s.Add(1)
+	s.Add(2)
+
+	c := s.Clone()
+	if c.Len() != 2 || !c.Has(1) || !c.Has(2) {
+		t.Fatalf("clone = %v, want {1, 2}", c)
+	}
+
+	c.Add(3)
+	c.Remove(1)
+	if s.Len() != 2 || !s.Has(1) || s.Has(3) {
+		t.Errorf("changing the clone changed the original to %v", s)
+	}
+
+	s.Clear()
+	if c.Len() != 2 || !c.Has(2) || !c.Has(3) {
+		t.Errorf("clearing the original changed the clone to %v", c)
+	}
+}
+
+func TestSetMerge(t *testing.T) {
+	s := NewSet[string]()
+	s.Add("a")
+	s.Add("b")
+	other := NewSet[string]()
+	other.Add("b")
+	other.Add("c")
+
+	s.Merge(other)
+	if s.Len() != 3 || !s.Has("a") || !s.Has("b") || !s.Has("c") {
+		t.Errorf("merged set = %v, want {a, b, c}", s)
+	}
+	if other.Len() != 2 || other.Has("a") {
+		t.Errorf("merge changed the other set to %v", other)
+	}
+
+	// Merging an empty or nil set is a no-op
+	s.Merge(NewSet[string]())
+	s.Merge(nil)
+	if s.Len() != 3 {
+		t.Errorf("merging an empty set gave %v", s)
+	}
+}
@@ -0,0 +1,77 @@
+package collision
+
+import "github.com/samredway/ebx/geom"
+
+// Grid is the one place that decides which cells of a map's collision layer
+// are solid, so everything sharing the map (the MovementSystem, enemy
+// pathfinding etc.) agrees. It reads the layer live, so SetTile changes show
+// up straight away, and tells listeners when a cell's solidity changes, e.g.
+// to update a flow field when a wall is destroyed
+type Grid struct {
+	tileMap   *Map
+	layer     int
+	solid     func(globalId int) bool // nil = every non-empty tile is solid
+	listeners []func(tx, ty int)
+}
+
+// Map returns the map the grid reads
+func (g *Grid) Map() *Map { return g.tileMap }
+
+// Layer returns the index of the collision layer
+func (g *Grid) Layer() int { return g.layer }
+
+// Solid reports whether a global tile ID is solid. Empty (0) tiles never are
+func (g *Grid) Solid(globalId int) bool {
+	globalId = StripFlipFlags(globalId)
+	if globalId == 0 {
+		return false
+	}
+	return g.solid == nil || g.solid(globalId)
+}
+
+// IsSolid reports whether the cell at tx, ty is solid: its tile is Solid and
+// blocks movement from every side. Cells holding one-way tiles (see
+// Map.SetTileBlock) can be entered so aren't solid, use Map.TileBlockFor for
+// their sides. Cells outside the map are solid
+func (g *Grid) IsSolid(tx, ty int) bool {
+	m := g.tileMap
+	if tx < 0 || ty < 0 || tx >= m.MapWidth || ty >= m.MapHeight {
+		return true
+	}
+	return m.solidTile(m.Layers[g.layer][ty*m.MapWidth+tx], g.solid)
+}
+
+// NearestFreeTile is Map.NearestFreeTile in the grid's layer, where only the
+// grid's solid cells block
+func (g *Grid) NearestFreeTile(p geom.Vec2) (geom.Vec2, bool) {
+	return g.tileMap.NearestFreeTileIf(p, g.layer, g.solid)
+}
+
+// OnChange registers fn to be called with a cell whenever SetTile changes
+// whether it is solid. Listeners are called in the order they were added
+func (g *Grid) OnChange(fn func(tx, ty int)) {
+	g.listeners = append(g.listeners, fn)
+}
+
+// tileSet is called by the map's SetTile
+func (g *Grid) tileSet(tx, ty, layer, oldId, newId int) {
+	m := g.tileMap
+	if layer != g.layer || m.solidTile(oldId, g.solid) == m.solidTile(newId, g.solid) {
+		return
+	}
+	for _, fn := range g.listeners {
+		fn(tx, ty)
+	}
+}
+
+// NewGrid is constructor for Grid. solid picks which tiles in the layer are
+// solid, given the global tile ID without flip flags. Pass nil for every
+// non-empty tile
+func NewGrid(m *Map, layer int, solid func(globalId int) bool) (*Grid, error) {
+	if _, err := m.LayerData(layer); err != nil {
+		return nil, err
+	}
+	g := &Grid{tileMap: m, layer: layer, solid: solid}
+	m.grids = append(m.grids, g)
+	return g, nil
+}
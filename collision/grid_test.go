@@ -0,0 +1,80 @@
+package collision
+
+import (
+	"testing"
+
+	"github.com/samredway/ebx/geom"
+)
+
+func TestGridIsSolidAfterSetTile(t *testing.T) {
+	tm := newTestMap(t, 3, 1, []int{1, 0, 2})
+	g, err := NewGrid(tm, 0, func(id int) bool { return id == 1 })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changed []geom.Vec2I
+	g.OnChange(func(tx, ty int) { changed = append(changed, geom.Vec2I{X: tx, Y: ty}) })
+
+	if !g.IsSolid(0, 0) || g.IsSolid(1, 0) || g.IsSolid(2, 0) {
+		t.Fatalf("IsSolid = %v, %v, %v, want true, false, false", g.IsSolid(0, 0), g.IsSolid(1, 0), g.IsSolid(2, 0))
+	}
+	if !g.IsSolid(-1, 0) || !g.IsSolid(3, 0) {
+		t.Error("cells outside the map aren't solid")
+	}
+
+	// Knock down the wall and build one from a solid tile
+	if err := tm.SetTile(0, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.SetTile(1, 0, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	// Swapping one non-solid tile for another changes nothing
+	if err := tm.SetTile(2, 0, 0, 3); err != nil {
+		t.Fatal(err)
+	}
+	if g.IsSolid(0, 0) || !g.IsSolid(1, 0) {
+		t.Errorf("after SetTile IsSolid = %v, %v, want false, true", g.IsSolid(0, 0), g.IsSolid(1, 0))
+	}
+	if want := []geom.Vec2I{{X: 0}, {X: 1}}; len(changed) != 2 || changed[0] != want[0] || changed[1] != want[1] {
+		t.Errorf("OnChange got %v, want %v", changed, want)
+	}
+}
+
+func TestGridOneWayTilesNotSolid(t *testing.T) {
+	tm := newTestMap(t, 3, 1, []int{1, 2, 0})
+	tm.SetTileBlock(2, BlockNorth)
+	g, err := NewGrid(tm, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !g.IsSolid(0, 0) {
+		t.Error("tile blocking every side isn't solid")
+	}
+	if g.IsSolid(1, 0) {
+		t.Error("one-way tile is solid")
+	}
+
+	// The one-way tile is the nearest free tile to the wall
+	got, ok := g.NearestFreeTile(geom.Vec2{X: 8, Y: 8})
+	if want := (geom.Vec2{X: 16}); !ok || got != want {
+		t.Errorf("NearestFreeTile = %v, %v, want %v, true", got, ok, want)
+	}
+}
+
+func TestNearestFreeTileIfUsesPredicate(t *testing.T) {
+	// Tile 2 is decorative, so standing on it is already free
+	tm := newTestMap(t, 3, 1, []int{1, 2, 0})
+	p := geom.Vec2{X: 20, Y: 4}
+	got, ok := tm.NearestFreeTileIf(p, 0, func(id int) bool { return id == 1 })
+	if !ok || got != p {
+		t.Errorf("NearestFreeTileIf = %v, %v, want %v, true", got, ok, p)
+	}
+
+	// Without the predicate every non-empty tile blocks
+	got, ok = tm.NearestFreeTile(p, 0)
+	if want := (geom.Vec2{X: 32}); !ok || got != want {
+		t.Errorf("NearestFreeTile = %v, %v, want %v, true", got, ok, want)
+	}
+}
@@ -0,0 +1,376 @@
+// Package collision tests and resolves boxes against the tile layers of a map.
+// It doesn't depend on Ebiten so movement and collision logic runs, and can be
+// tested, without a display. assetmgr.TileMap embeds a Map, so its methods are
+// also available on a loaded tilemap
+package collision
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"slices"
+
+	"github.com/samredway/ebitmx"
+	"github.com/samredway/ebx/geom"
+)
+
+// Map is the tile data of a map that collision reads: its size, layers of
+// global tile IDs and the directional collision of tiles
+type Map struct {
+	*ebitmx.EbitenMap                   // Embedded map data from ebitmx
+	blocks            map[int]TileBlock // Directional collision by global tile ID, unset = BlockAll
+	grids             []*Grid           // Collision grids told about SetTile
+}
+
+// TileBlock flags which sides of a tile block movement into it, allowing
+// one-way tiles such as a ledge you can walk down off but not back up
+type TileBlock uint8
+
+const (
+	BlockNorth TileBlock = 1 << iota // Blocks entering through the top edge (moving down)
+	BlockSouth                       // Blocks entering through the bottom edge (moving up)
+	BlockEast                        // Blocks entering through the right edge (moving left)
+	BlockWest                        // Blocks entering through the left edge (moving right)
+
+	BlockAll = BlockNorth | BlockSouth | BlockEast | BlockWest
+)
+
+// TileFlipFlags are the high bits Tiled sets on a global tile ID in layer data
+// to flip or rotate the tile
+const TileFlipFlags = 0xF0000000
+
+// StripFlipFlags returns a global tile ID from layer data without any Tiled
+// flip/rotation flags
+func StripFlipFlags(globalId int) int {
+	return globalId &^ TileFlipFlags
+}
+
+// SetTileBlock sets which sides of a tile (by global tile ID) block movement.
+// These can also be set in Tiled with the bool tile properties blockN, blockS,
+// blockE and blockW
+func (m *Map) SetTileBlock(globalId int, block TileBlock) {
+	m.blocks[globalId] = block
+}
+
+// TileBlockFor returns the blocking sides for a global tile ID. Empty tiles
+// block nothing and tiles without flags set block from every side
+func (m *Map) TileBlockFor(globalId int) TileBlock {
+	if globalId == 0 {
+		return 0
+	}
+	if block, ok := m.blocks[StripFlipFlags(globalId)]; ok {
+		return block
+	}
+	return BlockAll
+}
+
+// NumLayers returns the number of layers in the tilemap
+func (m *Map) NumLayers() int { return len(m.Layers) }
+
+// OverlapsTiles returns true if a position overlaps any tiles in a given layer
+// used to check collision for example
+func (m *Map) OverlapsTiles(x, y, w, h float64, layer int) (bool, error) {
+	hit, _, _, err := m.OverlapsTilesAt(x, y, w, h, layer)
+	return hit, err
+}
+
+// OverlapsTilesAt is OverlapsTiles but also returns the coords of the first
+// tile overlapped, scanning rows top to bottom and each row left to right,
+// e.g. so AI can tell which wall it hit. A box entirely outside the map
+// overlaps (it hits the world bounds) and gives its top left tile, which is
+// outside the map
+func (m *Map) OverlapsTilesAt(x, y, w, h float64, layer int) (hit bool, tx, ty int, err error) {
+	data, err := m.LayerData(layer)
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	// outside = collide with world bounds
+	covered := m.tileSpan(x, y, w, h)
+	span := m.ClampRect(covered)
+	if !covered.Empty() && span.Empty() {
+		return true, covered.Min.X, covered.Min.Y, nil
+	}
+
+	rowW := m.MapWidth
+	for ty := span.Min.Y; ty < span.Max.Y; ty++ {
+		base := ty * rowW
+		for tx := span.Min.X; tx < span.Max.X; tx++ {
+			if data[base+tx] != 0 {
+				return true, tx, ty, nil
+			}
+		}
+	}
+	return false, 0, 0, nil
+}
+
+// OverlappedTileIds returns the distinct non-zero global tile IDs a box
+// overlaps in a layer, in row order. Unlike OverlapsTiles nothing is resolved
+// and the area outside the map counts as empty, so it suits trigger layers
+// such as spikes or water that affect but don't block whatever is on them
+func (m *Map) OverlappedTileIds(x, y, w, h float64, layer int) ([]int, error) {
+	data, err := m.LayerData(layer)
+	if err != nil {
+		return nil, err
+	}
+
+	span := m.ClampRect(m.tileSpan(x, y, w, h))
+
+	var ids []int
+	for ty := span.Min.Y; ty < span.Max.Y; ty++ {
+		for tx := span.Min.X; tx < span.Max.X; tx++ {
+			id := data[ty*m.MapWidth+tx]
+			if id != 0 && !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// SetTile changes the tile at tx, ty in a layer to a global tile ID, e.g. 0 to
+// knock down a wall. The change is seen straight away by collision checks and
+// rendering as both read the layer data directly, and Grid listeners are told
+// if the cell's solidity changed
+func (m *Map) SetTile(tx, ty, layer, globalId int) error {
+	data, err := m.LayerData(layer)
+	if err != nil {
+		return err
+	}
+	if tx < 0 || ty < 0 || tx >= m.MapWidth || ty >= m.MapHeight {
+		return fmt.Errorf("tile (%d, %d) outside map of %dx%d tiles", tx, ty, m.MapWidth, m.MapHeight)
+	}
+	i := ty*m.MapWidth + tx
+	oldId := data[i]
+	data[i] = globalId
+	for _, g := range m.grids {
+		g.tileSet(tx, ty, layer, oldId, globalId)
+	}
+	return nil
+}
+
+// WorldToTile returns the coords of the tile containing a world position. The
+// coords may be outside the map
+func (m *Map) WorldToTile(p geom.Vec2) (tx, ty int) {
+	return int(math.Floor(p.X / float64(m.TileWidth))), int(math.Floor(p.Y / float64(m.TileHeight)))
+}
+
+// TileIdAt returns the global tile ID at tx, ty in a layer, e.g. to read a
+// biome or ground type layer for footstep sounds. Tiles outside the map and
+// invalid layers read as 0 (empty)
+func (m *Map) TileIdAt(tx, ty, layer int) int {
+	if layer < 0 || layer >= len(m.Layers) {
+		return 0
+	}
+	if tx < 0 || ty < 0 || tx >= m.MapWidth || ty >= m.MapHeight {
+		return 0
+	}
+	return m.Layers[layer][ty*m.MapWidth+tx]
+}
+
+// BlocksMove reports whether moving the box x, y, w, h by dx, dy would enter a
+// tile in the layer through one of its blocking sides. Move one axis at a time
+// so the side being entered is known. Tiles blocking from every side behave
+// exactly like OverlapsTiles, tiles blocking only some sides are ignored while
+// the box already overlaps them so you can always walk back out
+func (m *Map) BlocksMove(x, y, w, h, dx, dy float64, layer int) (bool, error) {
+	return m.BlocksMoveIf(x, y, w, h, dx, dy, layer, nil)
+}
+
+// BlocksMoveIf is BlocksMove where only tiles for which solid returns true can
+// block, e.g. to keep decorative tiles in a collision layer walkable. solid is
+// given the global tile ID without flip flags and is never called for empty
+// tiles. A nil solid treats every non-empty tile as solid, like BlocksMove
+func (m *Map) BlocksMoveIf(x, y, w, h, dx, dy float64, layer int, solid func(globalId int) bool) (bool, error) {
+	if len(m.blocks) == 0 && solid == nil {
+		return m.OverlapsTiles(x+dx, y+dy, w, h, layer)
+	}
+	data, err := m.LayerData(layer)
+	if err != nil {
+		return false, err
+	}
+
+	var side TileBlock
+	switch {
+	case dx > 0:
+		side = BlockWest
+	case dx < 0:
+		side = BlockEast
+	case dy > 0:
+		side = BlockNorth
+	case dy < 0:
+		side = BlockSouth
+	}
+
+	covered := m.tileSpan(x+dx, y+dy, w, h)
+	span := m.ClampRect(covered)
+	inside := m.tileSpan(x, y, w, h)
+
+	// outside = collide with world bounds
+	if !covered.Empty() && span.Empty() {
+		return true, nil
+	}
+
+	for ty := span.Min.Y; ty < span.Max.Y; ty++ {
+		for tx := span.Min.X; tx < span.Max.X; tx++ {
+			id := data[ty*m.MapWidth+tx]
+			if id == 0 || (solid != nil && !solid(StripFlipFlags(id))) {
+				continue
+			}
+			block := m.TileBlockFor(id)
+			if block == BlockAll {
+				return true, nil
+			}
+			alreadyInside := image.Pt(tx, ty).In(inside)
+			if block&side != 0 && !alreadyInside {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// tileSpan returns the range of tiles covered by a box in world coords, the
+// max values being exclusive. The box's far edges are exclusive too, so a box
+// exactly touching a tile doesn't cover it but one overlapping it by any
+// fraction of a pixel does. A box with no area covers no tiles, so never
+// collides. The range is not clamped to the map
+func (m *Map) tileSpan(x, y, w, h float64) image.Rectangle {
+	if w <= 0 || h <= 0 {
+		return image.Rectangle{}
+	}
+	tw := float64(m.TileWidth)
+	th := float64(m.TileHeight)
+
+	tx0 := int(math.Floor(x / tw))
+	ty0 := int(math.Floor(y / th))
+	tx1 := int(math.Ceil((x + w) / tw)) // exclusive Max
+	ty1 := int(math.Ceil((y + h) / th))
+	return image.Rectangle{Min: image.Pt(tx0, ty0), Max: image.Pt(tx1, ty1)}
+}
+
+// leavesMap reports whether moving box by dx, dy takes any of it further
+// outside the map than it already was, so a box partly outside can still move
+// back in
+func (m *Map) leavesMap(box geom.Rect, dx, dy float64) bool {
+	from := m.tileSpan(box.X, box.Y, box.W, box.H)
+	to := m.tileSpan(box.X+dx, box.Y+dy, box.W, box.H)
+	return (to.Min.X < 0 && to.Min.X < from.Min.X) ||
+		(to.Min.Y < 0 && to.Min.Y < from.Min.Y) ||
+		(to.Max.X > m.MapWidth && to.Max.X > from.Max.X) ||
+		(to.Max.Y > m.MapHeight && to.Max.Y > from.Max.Y)
+}
+
+// ClampRect returns the part of an area in tile coords that lies inside the
+// map. An area entirely outside the map gives an empty rect
+func (m *Map) ClampRect(area image.Rectangle) image.Rectangle {
+	return area.Intersect(image.Rect(0, 0, m.MapWidth, m.MapHeight))
+}
+
+// LayerData returns the global tile IDs of a layer, row by row, or an error if
+// there is no such layer. The slice is the map's own data, not a copy
+func (m *Map) LayerData(layer int) ([]int, error) {
+	if layer < 0 || layer >= len(m.Layers) {
+		return nil, fmt.Errorf("invalid layer index: %d (map has %d layers)", layer, len(m.Layers))
+	}
+	return m.Layers[layer], nil
+}
+
+// nearestFreeTileRadius is how many tiles out NearestFreeTile will search
+const nearestFreeTileRadius = 16
+
+// NearestFreeTile finds the nearest free tile to world position p in a layer,
+// e.g. to stop something spawning or teleporting into a wall. If p is already
+// on a free tile it is returned as is, otherwise the top left corner of the
+// nearest free tile is returned. The search works outwards ring by ring up to
+// nearestFreeTileRadius tiles away; ok is false if nothing free is in range.
+// Free tiles are empty or only block some sides (see SetTileBlock)
+func (m *Map) NearestFreeTile(p geom.Vec2, layer int) (geom.Vec2, bool) {
+	return m.NearestFreeTileIf(p, layer, nil)
+}
+
+// NearestFreeTileIf is NearestFreeTile where only tiles for which solid returns
+// true can block (see BlocksMoveIf), so it agrees with a Mover or Grid using
+// the same predicate
+func (m *Map) NearestFreeTileIf(p geom.Vec2, layer int, solid func(globalId int) bool) (geom.Vec2, bool) {
+	if layer < 0 || layer >= len(m.Layers) {
+		return geom.Vec2{}, false
+	}
+
+	tw := float64(m.TileWidth)
+	th := float64(m.TileHeight)
+	px := int(math.Floor(p.X / tw))
+	py := int(math.Floor(p.Y / th))
+
+	if m.isFree(px, py, layer, solid) {
+		return p, true
+	}
+
+	for r := 1; r <= nearestFreeTileRadius; r++ {
+		found := false
+		var best geom.Vec2
+		bestDist := math.Inf(1)
+
+		// Walk the square ring r tiles out, keeping the closest free tile
+		for ty := py - r; ty <= py+r; ty++ {
+			for tx := px - r; tx <= px+r; tx++ {
+				onRing := ty == py-r || ty == py+r || tx == px-r || tx == px+r
+				if !onRing || !m.isFree(tx, ty, layer, solid) {
+					continue
+				}
+				corner := geom.Vec2{X: float64(tx) * tw, Y: float64(ty) * th}
+				dist := math.Hypot(corner.X+tw/2-p.X, corner.Y+th/2-p.Y)
+				if dist < bestDist {
+					best, bestDist, found = corner, dist, true
+				}
+			}
+		}
+		if found {
+			return best, true
+		}
+	}
+	return geom.Vec2{}, false
+}
+
+// isFree reports whether a tile is inside the map and not solid in the given
+// layer
+func (m *Map) isFree(tx, ty, layer int, solid func(globalId int) bool) bool {
+	if tx < 0 || ty < 0 || tx >= m.MapWidth || ty >= m.MapHeight {
+		return false
+	}
+	return !m.solidTile(m.Layers[layer][ty*m.MapWidth+tx], solid)
+}
+
+// solidTile reports whether a tile can't be stood in: it is picked by solid
+// (nil = any non-empty tile) and blocks movement into it from every side.
+// Tiles blocking only some sides can be entered, and BlocksMove lets a box
+// already inside one walk back out
+func (m *Map) solidTile(globalId int, solid func(globalId int) bool) bool {
+	globalId = StripFlipFlags(globalId)
+	if globalId == 0 || (solid != nil && !solid(globalId)) {
+		return false
+	}
+	return m.TileBlockFor(globalId) == BlockAll
+}
+
+// NewMap creates a map of width x height tiles straight from layer data
+// (global tile IDs row by row, one slice per layer)
+func NewMap(width, height, tileW, tileH int, layers [][]int) (*Map, error) {
+	for i, data := range layers {
+		if len(data) != width*height {
+			return nil, fmt.Errorf("layer %d has %d tiles, want %dx%d", i, len(data), width, height)
+		}
+	}
+	return NewMapFromEbitmx(&ebitmx.EbitenMap{
+		TileWidth:  tileW,
+		TileHeight: tileH,
+		MapWidth:   width,
+		MapHeight:  height,
+		Layers:     layers,
+	}), nil
+}
+
+// NewMapFromEbitmx wraps map data parsed by ebitmx, e.g. from a .tmx file
+func NewMapFromEbitmx(m *ebitmx.EbitenMap) *Map {
+	return &Map{EbitenMap: m, blocks: map[int]TileBlock{}}
+}
@@ -0,0 +1,236 @@
+package collision
+
+import (
+	"image"
+	"slices"
+	"testing"
+
+	"github.com/samredway/ebx/geom"
+)
+
+// newTestMap builds a map of 16px tiles from layers of global tile IDs
+func newTestMap(t *testing.T, width, height int, layers ...[]int) *Map {
+	t.Helper()
+	tm, err := NewMap(width, height, 16, 16, layers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tm
+}
+
+func TestNearestFreeTile(t *testing.T) {
+	tm := newTestMap(t, 5, 3, []int{
+		1, 1, 1, 1, 1,
+		1, 1, 1, 0, 1,
+		1, 1, 1, 1, 1,
+	})
+
+	t.Run("inside a wall", func(t *testing.T) {
+		got, ok := tm.NearestFreeTile(geom.Vec2{X: 24, Y: 24}, 0)
+		if !ok {
+			t.Fatal("no free tile found")
+		}
+		if want := (geom.Vec2{X: 48, Y: 16}); got != want {
+			t.Errorf("NearestFreeTile = %v, want the free tile's corner %v", got, want)
+		}
+	})
+
+	t.Run("already free", func(t *testing.T) {
+		p := geom.Vec2{X: 50, Y: 20}
+		got, ok := tm.NearestFreeTile(p, 0)
+		if !ok || got != p {
+			t.Errorf("NearestFreeTile = %v, %v, want %v, true", got, ok, p)
+		}
+	})
+
+	t.Run("fully walled", func(t *testing.T) {
+		walled := newTestMap(t, 3, 3, []int{1, 1, 1, 1, 1, 1, 1, 1, 1})
+		if got, ok := walled.NearestFreeTile(geom.Vec2{X: 24, Y: 24}, 0); ok {
+			t.Errorf("NearestFreeTile = %v, want ok false", got)
+		}
+	})
+
+	t.Run("invalid layer", func(t *testing.T) {
+		if _, ok := tm.NearestFreeTile(geom.Vec2{}, 3); ok {
+			t.Error("want ok false for a missing layer")
+		}
+	})
+}
+
+func TestBlocksMoveOneWay(t *testing.T) {
+	// An 8x8 box stepping 8px into the centre tile of a 3x3 map from each side
+	moves := []struct {
+		name   string
+		x, y   float64
+		dx, dy float64
+		side   TileBlock
+	}{
+		{"moving down", 20, 4, 0, 8, BlockNorth},
+		{"moving up", 20, 36, 0, -8, BlockSouth},
+		{"moving left", 36, 20, -8, 0, BlockEast},
+		{"moving right", 4, 20, 8, 0, BlockWest},
+	}
+
+	for _, block := range []TileBlock{BlockNorth, BlockSouth, BlockEast, BlockWest, BlockAll} {
+		tm := newTestMap(t, 3, 3, []int{
+			0, 0, 0,
+			0, 2, 0,
+			0, 0, 0,
+		})
+		tm.SetTileBlock(2, block)
+
+		for _, m := range moves {
+			got, err := tm.BlocksMove(m.x, m.y, 8, 8, m.dx, m.dy, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := block&m.side != 0
+			if got != want {
+				t.Errorf("tile blocking %04b, %s: blocked = %v, want %v", block, m.name, got, want)
+			}
+		}
+
+		// Whatever the sides, a box already in the tile can always leave
+		if block != BlockAll {
+			got, err := tm.BlocksMove(20, 20, 8, 8, 0, -8, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got {
+				t.Errorf("tile blocking %04b: box inside the tile can't move out", block)
+			}
+		}
+	}
+}
+
+func TestBlocksMoveFullTileMatchesOverlap(t *testing.T) {
+	plain := newTestMap(t, 3, 3, []int{0, 0, 0, 0, 2, 0, 0, 0, 0})
+	flagged := newTestMap(t, 3, 3, []int{0, 0, 0, 0, 2, 0, 0, 0, 0})
+	flagged.SetTileBlock(2, BlockAll)
+	flagged.SetTileBlock(3, BlockNorth) // Any flags take BlocksMove off the fast path
+
+	for y := -8.0; y <= 48; y += 4 {
+		for x := -8.0; x <= 48; x += 4 {
+			for _, d := range []geom.Vec2{{X: 4}, {X: -4}, {Y: 4}, {Y: -4}} {
+				want, err := plain.OverlapsTiles(x+d.X, y+d.Y, 8, 8, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := flagged.BlocksMove(x, y, 8, 8, d.X, d.Y, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Fatalf("box at %v,%v moving %v: BlocksMove = %v, OverlapsTiles = %v", x, y, d, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestTileIdAt(t *testing.T) {
+	tm := newTestMap(t, 3, 2, []int{
+		1, 2, 3,
+		4, 5, 6,
+	})
+	tests := []struct {
+		name          string
+		tx, ty, layer int
+		want          int
+	}{
+		{"top left", 0, 0, 0, 1},
+		{"bottom right", 2, 1, 0, 6},
+		{"left of map", -1, 0, 0, 0},
+		{"above map", 0, -1, 0, 0},
+		{"right of map", 3, 0, 0, 0},
+		{"below map", 0, 2, 0, 0},
+		{"invalid layer", 0, 0, 1, 0},
+		{"negative layer", 0, 0, -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tm.TileIdAt(tt.tx, tt.ty, tt.layer); got != tt.want {
+				t.Errorf("TileIdAt(%d, %d, %d) = %d, want %d", tt.tx, tt.ty, tt.layer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClampRect(t *testing.T) {
+	tm := newTestMap(t, 4, 3, make([]int, 12))
+	tests := []struct {
+		name       string
+		area, want image.Rectangle
+	}{
+		{"inside", image.Rect(1, 1, 3, 2), image.Rect(1, 1, 3, 2)},
+		{"whole map", image.Rect(0, 0, 4, 3), image.Rect(0, 0, 4, 3)},
+		{"overhanging", image.Rect(-2, -1, 6, 5), image.Rect(0, 0, 4, 3)},
+		{"partly right", image.Rect(3, 1, 8, 2), image.Rect(3, 1, 4, 2)},
+		{"outside", image.Rect(5, 0, 7, 2), image.Rectangle{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tm.ClampRect(tt.area); got != tt.want {
+				t.Errorf("ClampRect(%v) = %v, want %v", tt.area, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayerData(t *testing.T) {
+	tm := newTestMap(t, 2, 1, []int{1, 2}, []int{3, 4})
+	data, err := tm.LayerData(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(data, []int{3, 4}) {
+		t.Errorf("LayerData(1) = %v, want [3 4]", data)
+	}
+	// The map's own data, not a copy
+	data[0] = 9
+	if got := tm.TileIdAt(0, 0, 1); got != 9 {
+		t.Errorf("tile after writing to LayerData = %d, want 9", got)
+	}
+
+	for _, layer := range []int{-1, 2} {
+		if _, err := tm.LayerData(layer); err == nil {
+			t.Errorf("LayerData(%d) didn't error", layer)
+		}
+	}
+}
+
+func TestOverlapsTilesAt(t *testing.T) {
+	tm := newTestMap(t, 4, 3, []int{
+		0, 0, 0, 0,
+		0, 0, 1, 1,
+		0, 1, 0, 0,
+	})
+	tests := []struct {
+		name       string
+		x, y, w, h float64
+		wantHit    bool
+		wantTile   image.Point
+	}{
+		{"empty", 2, 2, 12, 12, false, image.Point{}},
+		// Overlapping (2, 1), (3, 1) and (1, 2), rows scan first
+		{"first in row order", 20, 20, 40, 20, true, image.Pt(2, 1)},
+		{"second row only", 16, 36, 8, 8, true, image.Pt(1, 2)},
+		{"outside left", -40, 0, 8, 8, true, image.Pt(-3, 0)},
+		{"outside below", 0, 60, 8, 8, true, image.Pt(0, 3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hit, tx, ty, err := tm.OverlapsTilesAt(tt.x, tt.y, tt.w, tt.h, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hit != tt.wantHit || (hit && image.Pt(tx, ty) != tt.wantTile) {
+				t.Errorf("OverlapsTilesAt = %v at (%d, %d), want %v at %v", hit, tx, ty, tt.wantHit, tt.wantTile)
+			}
+		})
+	}
+
+	if _, _, _, err := tm.OverlapsTilesAt(0, 0, 8, 8, 1); err == nil {
+		t.Error("OverlapsTilesAt on an invalid layer didn't error")
+	}
+}
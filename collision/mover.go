@@ -0,0 +1,171 @@
+package collision
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/samredway/ebx/geom"
+)
+
+// Epsilon is the gap left between a box and a tile it is stopped at, so
+// floating point error doesn't leave the box overlapping the tile
+const Epsilon = 0.001
+
+// Mover moves boxes through a map one axis at a time, stopping them at the
+// blocking tiles of a collision layer. The engine's MovementSystem moves
+// entities' collision boxes with one
+type Mover struct {
+	Map   *Map
+	Layer int                     // Collision layer for boxes with a nil layer mask
+	Solid func(globalId int) bool // Which tiles block, nil = every non-empty tile
+}
+
+// Move moves box by dx and then dy, sliding along whatever it hits. It returns
+// the moved box and whether it was stopped on each axis. mask lists the layers
+// that block the box, nil for the Mover's Layer and empty for none. Even with
+// no layers the edges of the map still block
+func (mv *Mover) Move(box geom.Rect, dx, dy float64, mask []int) (moved geom.Rect, hitX, hitY bool) {
+	box.X, hitX = mv.MoveX(box, dx, mask)
+	box.Y, hitY = mv.MoveY(box, dy, mask)
+	return box, hitX, hitY
+}
+
+// MoveX moves box along the X axis by dx and returns its new X and whether a
+// tile was hit. It uses "predict and correct" logic:
+//  1. Calculate the new position after moving by dx
+//  2. Check if that position would enter any blocking tiles
+//  3. If yes, "push back" to the edge of the nearest blocking tile column
+func (mv *Mover) MoveX(box geom.Rect, dx float64, mask []int) (float64, bool) {
+	return resolveAxis(box.X, box.W, dx, float64(mv.Map.TileWidth), func(step float64) bool {
+		return mv.Blocks(box, step, 0, mask)
+	})
+}
+
+// MoveY is MoveX along the Y axis, returning the new Y
+func (mv *Mover) MoveY(box geom.Rect, dy float64, mask []int) (float64, bool) {
+	return resolveAxis(box.Y, box.H, dy, float64(mv.Map.TileHeight), func(step float64) bool {
+		return mv.Blocks(box, 0, step, mask)
+	})
+}
+
+// Blocks reports whether moving box by dx, dy is blocked (see Map.BlocksMoveIf)
+// in any of the layers in mask, or the Mover's Layer if mask is nil. Solid
+// only applies to the Mover's Layer, other layers in mask block with every
+// non-empty tile. An empty mask is only blocked by the map's edges, which it
+// can't cross. It panics on a layer the map doesn't have
+func (mv *Mover) Blocks(box geom.Rect, dx, dy float64, mask []int) bool {
+	if mask == nil {
+		return mv.blocksIn(box, dx, dy, mv.Layer)
+	}
+	if len(mask) == 0 {
+		return mv.Map.leavesMap(box, dx, dy)
+	}
+	for _, layer := range mask {
+		if mv.blocksIn(box, dx, dy, layer) {
+			return true
+		}
+	}
+	return false
+}
+
+// NearestFree returns box moved to the nearest spot where none of it is in a
+// blocking tile of the layers in mask (see Blocks), e.g. to stop something
+// spawning inside a wall. A box that is already clear is returned as is,
+// otherwise it is centred on the nearest tile, working outwards ring by ring
+// up to nearestFreeTileRadius tiles, where the whole box is clear. ok is
+// false if there is no such tile in range
+func (mv *Mover) NearestFree(box geom.Rect, mask []int) (free geom.Rect, ok bool) {
+	if !mv.Blocks(box, 0, 0, mask) {
+		return box, true
+	}
+
+	tw := float64(mv.Map.TileWidth)
+	th := float64(mv.Map.TileHeight)
+	centre := box.Centre()
+	px, py := mv.Map.WorldToTile(centre)
+
+	// Ring 0 is the tile the box's centre is on
+	for r := 0; r <= nearestFreeTileRadius; r++ {
+		bestDist := math.Inf(1)
+
+		// Walk the square ring r tiles out, keeping the closest clear spot
+		for ty := py - r; ty <= py+r; ty++ {
+			for tx := px - r; tx <= px+r; tx++ {
+				onRing := ty == py-r || ty == py+r || tx == px-r || tx == px+r
+				if !onRing {
+					continue
+				}
+				at := box
+				at.X = (float64(tx)+0.5)*tw - box.W/2
+				at.Y = (float64(ty)+0.5)*th - box.H/2
+				if mv.Blocks(at, 0, 0, mask) {
+					continue
+				}
+				if dist := math.Hypot(at.X-box.X, at.Y-box.Y); dist < bestDist {
+					free, bestDist, ok = at, dist, true
+				}
+			}
+		}
+		if ok {
+			return free, true
+		}
+	}
+	return box, false
+}
+
+func (mv *Mover) blocksIn(box geom.Rect, dx, dy float64, layer int) bool {
+	var solid func(globalId int) bool
+	if layer == mv.Layer {
+		solid = mv.Solid
+	}
+	blocked, err := mv.Map.BlocksMoveIf(box.X, box.Y, box.W, box.H, dx, dy, layer, solid)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to check tile collision in layer %d: %v", layer, err))
+	}
+	return blocked
+}
+
+// resolveAxis moves a box edge, of length size, by d along one axis.
+// blocked reports whether moving by a step along the axis hits a tile. When
+// the full move is blocked every tile column/row the box would enter is
+// checked nearest first, so a large box, or a fast one, stops at the first
+// tile it actually hits rather than at whichever tile its leading edge ends
+// up in. Returns the new min and whether a tile was hit
+func resolveAxis(edge, size, d, tile float64, blocked func(step float64) bool) (float64, bool) {
+	if !blocked(d) {
+		return edge + d, false
+	}
+
+	switch {
+	case d > 0:
+		// Moving right/down - the far edge is in column/row last
+		oldLast := int(math.Ceil((edge+size)/tile)) - 1
+		newLast := int(math.Ceil((edge+d+size)/tile)) - 1
+		for c := oldLast + 1; c <= newLast; c++ {
+			// Step just far enough for the far edge to enter c
+			step := math.Min(d, float64(c)*tile-edge-size+Epsilon)
+			if blocked(step) {
+				// Push back: near edge of the blocking tile minus our size minus safety gap
+				return float64(c)*tile - size - Epsilon, true
+			}
+		}
+		// Already overlapping a tile, push back out of the far edge's tile
+		return float64(newLast)*tile - size - Epsilon, true
+
+	case d < 0:
+		// Moving left/up - the near edge is in column/row first
+		oldFirst := int(math.Floor(edge / tile))
+		newFirst := int(math.Floor((edge + d) / tile))
+		for c := oldFirst - 1; c >= newFirst; c-- {
+			// Step just far enough for the near edge to enter c
+			step := math.Max(d, float64(c+1)*tile-Epsilon-edge)
+			if blocked(step) {
+				// Push back: far edge of the blocking tile plus safety gap
+				return float64(c+1)*tile + Epsilon, true
+			}
+		}
+		// Already overlapping a tile, push back out of the near edge's tile
+		return (math.Floor((edge+d)/tile)+1)*tile + Epsilon, true
+	}
+	return edge, true
+}
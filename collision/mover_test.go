@@ -0,0 +1,238 @@
+package collision
+
+import (
+	"math"
+	"testing"
+
+	"github.com/samredway/ebx/geom"
+)
+
+// roomMap is a 6x4 tile room walled all round, the inside spanning 16-80 by
+// 16-48 px
+func roomMap(t *testing.T) *Map {
+	return newTestMap(t, 6, 4, []int{
+		1, 1, 1, 1, 1, 1,
+		1, 0, 0, 0, 0, 1,
+		1, 0, 0, 0, 0, 1,
+		1, 1, 1, 1, 1, 1,
+	})
+}
+
+// near reports whether a and b are within float error of each other
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestMoverSlidesIntoCorner(t *testing.T) {
+	mv := &Mover{Map: roomMap(t)}
+	box := geom.Rect{X: 20, Y: 20, W: 8, H: 8}
+
+	// Move diagonally down right a frame at a time, as the MovementSystem does
+	var hitX, hitY bool
+	for range 20 {
+		box, hitX, hitY = mv.Move(box, 5, 3, nil)
+		if box.X+box.W > 80 || box.Y+box.H > 48 {
+			t.Fatalf("box %v entered the wall", box)
+		}
+	}
+
+	if !hitX || !hitY {
+		t.Errorf("hit = %v, %v, want both axes blocked in the corner", hitX, hitY)
+	}
+	if want := 80 - 8 - Epsilon; !near(box.X, want) {
+		t.Errorf("X = %v, want flush with the right wall at %v", box.X, want)
+	}
+	if want := 48 - 8 - Epsilon; !near(box.Y, want) {
+		t.Errorf("Y = %v, want flush with the floor at %v", box.Y, want)
+	}
+}
+
+func TestMoverFreeMove(t *testing.T) {
+	mv := &Mover{Map: roomMap(t)}
+	box := geom.Rect{X: 20, Y: 20, W: 8, H: 8}
+
+	got, hitX, hitY := mv.Move(box, 4, -2, nil)
+	if hitX || hitY {
+		t.Errorf("hit = %v, %v, want no hits in open space", hitX, hitY)
+	}
+	if want := (geom.Rect{X: 24, Y: 18, W: 8, H: 8}); got != want {
+		t.Errorf("Move = %v, want %v", got, want)
+	}
+}
+
+func TestMoverNoSubPixelSinking(t *testing.T) {
+	mv := Mover{Map: roomMap(t)}
+	box := geom.Rect{X: 20, Y: 48 - 8 - Epsilon, W: 8, H: 8}
+
+	// Resting on the floor, a move of less than a pixel is still blocked
+	moved, _, hitY := mv.Move(box, 0, 0.25, nil)
+	if !hitY || !near(moved.Y, box.Y) {
+		t.Errorf("Y = %v, hitY = %v, want blocked at %v", moved.Y, hitY, box.Y)
+	}
+}
+
+// hallMap is a 10x6 tile room walled all round with a one tile pillar at
+// column 6, row 2, the inside spanning 16-144 by 16-80 px
+func hallMap(t *testing.T) *Map {
+	return newTestMap(t, 10, 6, []int{
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 0, 0, 0, 0, 0, 0, 0, 0, 1,
+		1, 0, 0, 0, 0, 0, 1, 0, 0, 1,
+		1, 0, 0, 0, 0, 0, 0, 0, 0, 1,
+		1, 0, 0, 0, 0, 0, 0, 0, 0, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	})
+}
+
+func TestMoverLargeBox(t *testing.T) {
+	mv := &Mover{Map: hallMap(t)}
+
+	tests := []struct {
+		name  string
+		box   geom.Rect
+		dx    float64
+		wantX float64
+	}{
+		// The pillar only touches the middle row the box spans
+		{"stops at pillar", geom.Rect{X: 16, Y: 16, W: 48, H: 48}, 40, 96 - 48 - Epsilon},
+		// A fast move stops at the pillar, not the far wall its edge lands in
+		{"fast move stops at pillar", geom.Rect{X: 16, Y: 16, W: 48, H: 48}, 200, 96 - 48 - Epsilon},
+		// Already 4px into every row of the right wall it spans
+		{"pushed out of wall", geom.Rect{X: 100, Y: 32, W: 48, H: 48}, 2, 144 - 48 - Epsilon},
+		{"pushed out moving left", geom.Rect{X: 12, Y: 32, W: 48, H: 48}, -2, 16 + Epsilon},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, hit := mv.MoveX(tt.box, tt.dx, nil)
+			if !hit || !near(x, tt.wantX) {
+				t.Errorf("X = %v, hit = %v, want stopped at %v", x, hit, tt.wantX)
+			}
+		})
+	}
+}
+
+func TestMoverSolidPredicate(t *testing.T) {
+	const flippedDecor = 2 | 0x80000000 // Tile 2 flipped horizontally
+	tm := newTestMap(t, 4, 1, []int{0, 2, flippedDecor, 1})
+	var seen []int
+	mv := &Mover{Map: tm, Solid: func(id int) bool {
+		seen = append(seen, id)
+		return id == 1
+	}}
+
+	// Walks over the decorative tiles, stops at the solid one
+	x, hit := mv.MoveX(geom.Rect{X: 0, Y: 4, W: 8, H: 8}, 60, nil)
+	if want := 48 - 8 - Epsilon; !hit || !near(x, want) {
+		t.Errorf("X = %v, hit = %v, want stopped at tile 1 at %v", x, hit, want)
+	}
+	if len(seen) == 0 {
+		t.Error("Solid was never called")
+	}
+	for _, id := range seen {
+		if id&TileFlipFlags != 0 || id == 0 {
+			t.Errorf("Solid called with %#x, want masked non-empty ids", id)
+		}
+	}
+
+	// The default treats every non-empty tile as solid
+	mv.Solid = nil
+	x, hit = mv.MoveX(geom.Rect{X: 0, Y: 4, W: 8, H: 8}, 60, nil)
+	if want := 16 - 8 - Epsilon; !hit || !near(x, want) {
+		t.Errorf("default X = %v, hit = %v, want stopped at the first tile at %v", x, hit, want)
+	}
+}
+
+func TestMoverEmptyMaskKeepsMapEdges(t *testing.T) {
+	mv := &Mover{Map: roomMap(t)}
+	mask := []int{}
+
+	// Passes through the wall but not off the map
+	x, hit := mv.MoveX(geom.Rect{X: 60, Y: 20, W: 8, H: 8}, 100, mask)
+	if want := 96 - 8 - Epsilon; !hit || !near(x, want) {
+		t.Errorf("X = %v, hit = %v, want stopped at the map edge at %v", x, hit, want)
+	}
+	y, hit := mv.MoveY(geom.Rect{X: 20, Y: 20, W: 8, H: 8}, -100, mask)
+	if want := Epsilon; !hit || !near(y, want) {
+		t.Errorf("Y = %v, hit = %v, want stopped at the map edge at %v", y, hit, want)
+	}
+
+	// A box already partly off the map can move back on
+	x, hit = mv.MoveX(geom.Rect{X: -4, Y: 20, W: 8, H: 8}, 2, mask)
+	if hit || !near(x, -2) {
+		t.Errorf("X = %v, hit = %v, want -2 moving back onto the map", x, hit)
+	}
+}
+
+func TestMoverSolidOnlyInCollisionLayer(t *testing.T) {
+	// Tile 2 is decoration in the collision layer but a wall in layer 1
+	tm := newTestMap(t, 4, 1, []int{0, 2, 0, 0}, []int{0, 0, 2, 0})
+	mv := &Mover{Map: tm, Layer: 0, Solid: func(id int) bool { return id != 2 }}
+
+	box := geom.Rect{X: 0, Y: 4, W: 8, H: 8}
+	var hit bool
+	for range 15 {
+		box, hit, _ = mv.Move(box, 4, 0, []int{0, 1})
+	}
+	if x, want := box.X, 32-8-Epsilon; !hit || !near(x, want) {
+		t.Errorf("X = %v, hit = %v, want stopped by layer 1's tile at %v", x, hit, want)
+	}
+}
+
+func TestMoverNearestFree(t *testing.T) {
+	mv := &Mover{Map: roomMap(t)}
+
+	t.Run("clear", func(t *testing.T) {
+		box := geom.Rect{X: 20, Y: 20, W: 8, H: 8}
+		if got, ok := mv.NearestFree(box, nil); !ok || got != box {
+			t.Errorf("NearestFree = %v, %v, want %v unmoved", got, ok, box)
+		}
+	})
+
+	t.Run("edge in wall", func(t *testing.T) {
+		// The centre is on a free tile but the left edge is in the wall
+		box := geom.Rect{X: 14, Y: 20, W: 8, H: 8}
+		got, ok := mv.NearestFree(box, nil)
+		if !ok || mv.Blocks(got, 0, 0, nil) {
+			t.Fatalf("NearestFree = %v, %v, want a clear box", got, ok)
+		}
+		if want := (geom.Rect{X: 20, Y: 20, W: 8, H: 8}); got != want {
+			t.Errorf("NearestFree = %v, want centred on the tile at %v", got, want)
+		}
+	})
+
+	t.Run("every masked layer", func(t *testing.T) {
+		// Layer 1 blocks the tile next to the wall, so the box goes one further
+		tm := newTestMap(t, 4, 1, []int{1, 0, 0, 0}, []int{0, 1, 0, 0})
+		mv := &Mover{Map: tm}
+		got, ok := mv.NearestFree(geom.Rect{X: 4, Y: 4, W: 8, H: 8}, []int{0, 1})
+		if want := (geom.Rect{X: 36, Y: 4, W: 8, H: 8}); !ok || got != want {
+			t.Errorf("NearestFree = %v, %v, want %v", got, ok, want)
+		}
+	})
+}
+
+func TestMoverPassesClearedTile(t *testing.T) {
+	tm := newTestMap(t, 6, 3, []int{
+		1, 1, 1, 1, 1, 1,
+		1, 0, 0, 1, 0, 1,
+		1, 1, 1, 1, 1, 1,
+	})
+	mv := &Mover{Map: tm}
+	start := geom.Rect{X: 20, Y: 20, W: 8, H: 8}
+
+	box, hitX, _ := mv.Move(start, 30, 0, nil)
+	if !hitX || box.X+box.W > 48 {
+		t.Fatalf("box %v got past the wall at 48", box)
+	}
+
+	// Knock the wall down
+	if err := tm.SetTile(3, 1, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	box, hitX, _ = mv.Move(start, 30, 0, nil)
+	if hitX || box.X != 50 {
+		t.Errorf("box %v after clearing the wall, want moved the full 30px to X 50", box)
+	}
+
+	if err := tm.SetTile(6, 1, 0, 0); err == nil {
+		t.Error("SetTile outside the map didn't error")
+	}
+}
@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"math"
+	"math/rand/v2"
+
+	"github.com/samredway/ebx/assetmgr"
+	"github.com/samredway/ebx/geom"
+)
+
+// AIBehaviour is what an AI entity does when it can see its target
+type AIBehaviour int
+
+const (
+	AIIdle  AIBehaviour = iota // Ignore the target
+	AIChase                    // Move towards the target
+	AIFlee                     // Move away from the target
+)
+
+// aiDeadZone is how close px on an axis counts as lined up with the target, so
+// chasing entities don't jitter back and forth around it
+const aiDeadZone = 1.0
+
+// Wandering entities pause for a random time in this range (seconds) between moves
+const (
+	aiWanderMinPause = 0.5
+	aiWanderMaxPause = 2.0
+)
+
+// AIComponent holds per entity AI settings read by ChaseScript, so different
+// enemies can share one script and differ only by data
+type AIComponent struct {
+	SightRange float64     // Distance px at which the target is noticed
+	Speed      float64     // Movement speed px/s while reacting (0 = keep Movement.Speed)
+	Behaviour  AIBehaviour // What to do when the target is in range
+
+	// WanderRadius is how far px to meander to random spots while the target
+	// isn't seen (0 = stand still)
+	WanderRadius float64
+	wander       aiWander
+}
+
+// aiWander is the state of an entity's wandering, kept per entity so entities
+// can share a ChaseScript
+type aiWander struct {
+	target geom.Vec2 // Spot being walked to
+	active bool      // Walking to target
+	moving bool      // A direction was set last update
+	pause  float64   // Seconds left to stand still
+}
+
+// ChaseScript is a generic Script that reacts to Target according to the
+// entity's AIComponent, setting Movement.DesiredDir for the MovementSystem
+type ChaseScript struct {
+	Target *Entity
+
+	// TileMap and CollisionLayer are optional. When set wandering entities
+	// only pick spots on free tiles (see TileMap.NearestFreeTile)
+	TileMap        *assetmgr.TileMap
+	CollisionLayer int
+
+	// Grid is optional and used instead of TileMap and CollisionLayer, so
+	// wander spots agree with a MovementSystem sharing the grid about which
+	// tiles are solid (see MovementSystem.SetCollisionGrid)
+	Grid *assetmgr.CollisionGrid
+}
+
+func (cs *ChaseScript) Update(e *Entity, dt float64) {
+	ai, m := e.AI, e.Movement
+	if ai == nil || m == nil || e.Position == nil {
+		return
+	}
+
+	dir, seen := cs.seek(e)
+	if !seen || ai.Behaviour == AIIdle {
+		m.DesiredDir = cs.wander(e, dt)
+		return
+	}
+	ai.wander = aiWander{}
+
+	if ai.Speed > 0 {
+		m.Speed = ai.Speed
+	}
+	if ai.Behaviour == AIFlee {
+		dir = geom.Vec2I{X: -dir.X, Y: -dir.Y}
+	}
+	m.DesiredDir = dir
+}
+
+// seek returns the direction towards the target and whether it is within
+// sight range
+func (cs *ChaseScript) seek(e *Entity) (geom.Vec2I, bool) {
+	if cs.Target == nil || cs.Target.Dead || cs.Target.Despawned || cs.Target.Position == nil {
+		return geom.Vec2I{}, false
+	}
+	dx := cs.Target.Position.X - e.Position.X
+	dy := cs.Target.Position.Y - e.Position.Y
+	if math.Hypot(dx, dy) > e.AI.SightRange {
+		return geom.Vec2I{}, false
+	}
+	return geom.Vec2I{X: axisDir(dx), Y: axisDir(dy)}, true
+}
+
+// wander returns the direction to meander in while the target isn't seen,
+// walking to random free spots within WanderRadius with pauses between them
+func (cs *ChaseScript) wander(e *Entity, dt float64) geom.Vec2I {
+	w := &e.AI.wander
+	if e.AI.WanderRadius <= 0 {
+		*w = aiWander{}
+		return geom.Vec2I{}
+	}
+
+	// Blocked by a wall or another entity, give up on this spot
+	if w.active && w.moving && !e.Movement.IsMoving {
+		cs.arrive(w)
+	}
+	w.moving = false
+
+	if w.pause > 0 {
+		w.pause -= dt
+		return geom.Vec2I{}
+	}
+	if !w.active && !cs.pickWanderTarget(e) {
+		w.pause = aiWanderMaxPause
+		return geom.Vec2I{}
+	}
+
+	// Within a frame's move of the spot on an axis counts as there, so the
+	// entity doesn't overshoot and turn back
+	step := max(aiDeadZone, e.Movement.Speed*dt)
+	dir := geom.Vec2I{
+		X: stepDir(w.target.X-e.Position.X, step),
+		Y: stepDir(w.target.Y-e.Position.Y, step),
+	}
+	if dir == (geom.Vec2I{}) {
+		cs.arrive(w)
+		return dir
+	}
+	w.moving = true
+	return dir
+}
+
+// pickWanderTarget chooses a random spot within WanderRadius, moved onto the
+// nearest free tile if a Grid or TileMap is set. false if no free spot was found
+func (cs *ChaseScript) pickWanderTarget(e *Entity) bool {
+	angle := rand.Float64() * 2 * math.Pi
+	dist := rand.Float64() * e.AI.WanderRadius
+	target := geom.Vec2{
+		X: e.Position.X + math.Cos(angle)*dist,
+		Y: e.Position.Y + math.Sin(angle)*dist,
+	}
+	if cs.Grid != nil || cs.TileMap != nil {
+		var free geom.Vec2
+		var ok bool
+		if cs.Grid != nil {
+			free, ok = cs.Grid.NearestFreeTile(target)
+		} else {
+			free, ok = cs.TileMap.NearestFreeTile(target, cs.CollisionLayer)
+		}
+		if !ok {
+			return false
+		}
+		target = free
+	}
+	e.AI.wander.target = target
+	e.AI.wander.active = true
+	return true
+}
+
+// arrive ends the current wander move and pauses before the next
+func (cs *ChaseScript) arrive(w *aiWander) {
+	w.active = false
+	w.moving = false
+	w.pause = aiWanderMinPause + rand.Float64()*(aiWanderMaxPause-aiWanderMinPause)
+}
+
+// axisDir turns a distance on one axis into -1, 0 or 1
+func axisDir(d float64) int {
+	return stepDir(d, aiDeadZone)
+}
+
+// stepDir turns a distance on one axis into -1, 0 or 1, 0 when it is within
+// deadZone
+func stepDir(d, deadZone float64) int {
+	switch {
+	case d > deadZone:
+		return 1
+	case d < -deadZone:
+		return -1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/samredway/ebx/geom"
+)
+
+func TestWanderArrivesWithoutOvershoot(t *testing.T) {
+	ents := NewEntityManager()
+	e := mover(20, 20, 200, geom.Vec2I{})
+	e.AI = &AIComponent{WanderRadius: 40}
+	ents.Add(e)
+	ms := NewMovementSystem(ents, roomMap(t), 0)
+	cs := &ChaseScript{}
+	const dt = 1.0 / 60
+
+	target := geom.Vec2{X: 51.5, Y: 20}
+	e.AI.wander = aiWander{target: target, active: true}
+	for range 120 {
+		cs.Update(e, dt)
+		ms.Update(dt)
+		if e.Position.X > target.X+1e-9 || e.Position.Y > target.Y+1e-9 {
+			t.Fatalf("overshot the spot %v to %v", target, e.Position.Vec2)
+		}
+		if !e.AI.wander.active {
+			break
+		}
+	}
+
+	if e.AI.wander.active {
+		t.Fatalf("never arrived at %v, at %v", target, e.Position.Vec2)
+	}
+	step := e.Movement.Speed * dt
+	if d := math.Hypot(target.X-e.Position.X, target.Y-e.Position.Y); d > step*math.Sqrt2 {
+		t.Errorf("arrived %v px from the spot, want within a frame's move %v", d, step)
+	}
+}
+
+func TestWanderPicksFreeSpots(t *testing.T) {
+	ents := NewEntityManager()
+	e := mover(40, 28, 60, geom.Vec2I{})
+	e.AI = &AIComponent{WanderRadius: 64}
+	ents.Add(e)
+	tm := roomMap(t)
+	ms := NewMovementSystem(ents, tm, 0)
+	cs := &ChaseScript{TileMap: tm}
+
+	moved := false
+	for range 600 {
+		cs.Update(e, 1.0/60)
+		if w := e.AI.wander; w.active {
+			tx, ty := tm.WorldToTile(w.target)
+			if tm.TileIdAt(tx, ty, 0) != 0 {
+				t.Fatalf("wander spot %v is in a wall", w.target)
+			}
+		}
+		ms.Update(1.0 / 60)
+		moved = moved || e.Movement.IsMoving
+	}
+	if !moved {
+		t.Error("never wandered")
+	}
+}
+
+func TestChaseCoincidentTarget(t *testing.T) {
+	ents := NewEntityManager()
+	player := mover(40, 28, 0, geom.Vec2I{})
+	slime := mover(40, 28, 60, geom.Vec2I{})
+	slime.AI = &AIComponent{SightRange: 100, Behaviour: AIChase}
+	ents.Add(player)
+	ents.Add(slime)
+	ms := NewMovementSystem(ents, roomMap(t), 0)
+	cs := &ChaseScript{Target: player}
+
+	for range 10 {
+		cs.Update(slime, 1.0/60)
+		ms.Update(1.0 / 60)
+	}
+	if !geom.Finite(slime.Position.Vec2) || !geom.Finite(slime.Movement.Velocity) {
+		t.Errorf("position %v, velocity %v, want finite", slime.Position.Vec2, slime.Movement.Velocity)
+	}
+	if slime.Movement.DesiredDir != (geom.Vec2I{}) {
+		t.Errorf("DesiredDir = %v on top of the target, want zero", slime.Movement.DesiredDir)
+	}
+	if n := geom.Normalize(geom.Vec2{}); n != (geom.Vec2{}) {
+		t.Errorf("Normalize(zero) = %v, want zero", n)
+	}
+}
+
+func TestChaseScriptPerEntityConfig(t *testing.T) {
+	ents := NewEntityManager()
+	player := mover(70, 28, 0, geom.Vec2I{})
+	// Both 50px from the player, one shared script
+	hound := mover(20, 28, 30, geom.Vec2I{})
+	hound.AI = &AIComponent{SightRange: 80, Speed: 90, Behaviour: AIChase}
+	slime := mover(20, 28, 30, geom.Vec2I{})
+	slime.AI = &AIComponent{SightRange: 40, Speed: 90, Behaviour: AIChase}
+	for _, e := range []*Entity{player, hound, slime} {
+		ents.Add(e)
+	}
+	ms := NewMovementSystem(ents, roomMap(t), 0)
+	cs := &ChaseScript{Target: player}
+	hound.Script, slime.Script = cs, cs
+
+	for range 10 {
+		ents.Update(1.0 / 60)
+		ms.Update(1.0 / 60)
+	}
+	if hound.Movement.DesiredDir != (geom.Vec2I{X: 1}) || hound.Movement.Speed != 90 {
+		t.Errorf("hound DesiredDir %v at speed %v, want chasing right at 90",
+			hound.Movement.DesiredDir, hound.Movement.Speed)
+	}
+	if hound.Position.X <= 20 {
+		t.Errorf("hound at X %v, want moved towards the player", hound.Position.X)
+	}
+	if slime.Movement.DesiredDir != (geom.Vec2I{}) || slime.Movement.Speed != 30 || slime.Position.X != 20 {
+		t.Errorf("slime DesiredDir %v at speed %v and X %v, want out of sight and still",
+			slime.Movement.DesiredDir, slime.Movement.Speed, slime.Position.X)
+	}
+}
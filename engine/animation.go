@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Animation is a run of frames to show in turn. FlipX draws every frame
+// mirrored, so e.g. "walk_left" can reuse the frames of "walk_right" (see
+// Animations.Mirror)
+type Animation struct {
+	Frames []*ebiten.Image
+	FlipX  bool
+}
+
+// Apply shows a frame of the animation on r, wrapping frame to the number of
+// frames, along with the animation's flip
+func (a Animation) Apply(r *RenderComponent, frame int) {
+	if len(a.Frames) == 0 {
+		return
+	}
+	r.Img = a.Frames[frame%len(a.Frames)]
+	r.FlipX = a.FlipX
+}
+
+// Animations holds animations by name, e.g. "walk_right"
+type Animations map[string]Animation
+
+// NewAnimations wraps named runs of frames, as returned by
+// assetmgr.DirectionalAnimations, as unflipped Animations
+func NewAnimations(frames map[string][]*ebiten.Image) Animations {
+	anims := make(Animations, len(frames))
+	for name, f := range frames {
+		anims[name] = Animation{Frames: f}
+	}
+	return anims
+}
+
+// Add adds an animation of frames under name
+func (a Animations) Add(name string, frames []*ebiten.Image) {
+	a[name] = Animation{Frames: frames}
+}
+
+// Mirror adds name as the frames of the from animation drawn flipped, so
+// only one facing needs to be in the sprite sheet. The frames are shared,
+// not copied. Mirroring an already mirrored animation flips it back
+func (a Animations) Mirror(name, from string) error {
+	src, ok := a[from]
+	if !ok {
+		return fmt.Errorf("no animation %s to mirror as %s", from, name)
+	}
+	a[name] = Animation{Frames: src.Frames, FlipX: !src.FlipX}
+	return nil
+}
+
+// Crossfade tracks a short timed blend from an outgoing image to whatever the
+// entity shows next, e.g. when switching from an idle to a walk animation.
+// Drive it from a Script and call Apply each frame so the RenderSystem draws
+// both images with complementary alpha while the blend is running
+type Crossfade struct {
+	From     *ebiten.Image // Outgoing image, nil when not blending
+	Duration float64       // Blend length in seconds
+	elapsed  float64
+}
+
+// Start begins a blend out of the from image. A duration <= 0 is an instant
+// cut and cancels any blend in progress
+func (cf *Crossfade) Start(from *ebiten.Image, duration float64) {
+	if duration <= 0 {
+		cf.From = nil
+		return
+	}
+	cf.From = from
+	cf.Duration = duration
+	cf.elapsed = 0
+}
+
+// Update advances the blend and ends it once Duration has passed
+func (cf *Crossfade) Update(dt float64) {
+	if cf.From == nil {
+		return
+	}
+	cf.elapsed += dt
+	if cf.elapsed >= cf.Duration {
+		cf.From = nil
+	}
+}
+
+// Alpha returns the opacity of the incoming image, going from 0 to 1 over the
+// blend. It is 1 when no blend is running or Duration isn't > 0
+func (cf *Crossfade) Alpha() float64 {
+	if cf.From == nil || cf.Duration <= 0 {
+		return 1
+	}
+	return cf.elapsed / cf.Duration
+}
+
+// Apply copies the blend state on to the render component
+func (cf *Crossfade) Apply(r *RenderComponent) {
+	r.FadeFrom = cf.From
+	r.FadeAlpha = cf.Alpha()
+}
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestCrossfadeAlpha(t *testing.T) {
+	from := ebiten.NewImage(1, 1)
+	var cf Crossfade
+	if got := cf.Alpha(); got != 1 {
+		t.Errorf("zero value Alpha = %v, want 1", got)
+	}
+
+	cf.Start(from, 1)
+	if got := cf.Alpha(); got != 0 {
+		t.Errorf("Alpha at the start = %v, want 0", got)
+	}
+	for _, want := range []float64{0.25, 0.5, 0.75} {
+		cf.Update(0.25)
+		if got := cf.Alpha(); !near(got, want) || cf.From != from {
+			t.Errorf("Alpha = %v blending %v, want %v and still blending", got, cf.From != nil, want)
+		}
+	}
+	cf.Update(0.25)
+	if got := cf.Alpha(); got != 1 || cf.From != nil {
+		t.Errorf("after Duration Alpha = %v blending %v, want 1 and done", got, cf.From != nil)
+	}
+
+	// From set directly with no Duration is a cut, not NaN or Inf
+	cf = Crossfade{From: from}
+	if got := cf.Alpha(); got != 1 {
+		t.Errorf("Alpha with From and no Duration = %v, want 1", got)
+	}
+	cf.Start(from, 0)
+	if got := cf.Alpha(); got != 1 || cf.From != nil {
+		t.Errorf("Start with duration 0 gave Alpha %v blending %v, want a cut", got, cf.From != nil)
+	}
+}
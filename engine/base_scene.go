@@ -1,10 +1,19 @@
 package engine
 
 import (
+	"reflect"
+	"slices"
+
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/samredway/ebx/geom"
 )
 
+// Deallocator is a resource holding GPU memory that can be freed, such as an
+// *ebiten.Image or *assetmgr.Assets
+type Deallocator interface {
+	Deallocate()
+}
+
 // BaseScene provides default implementations of the Scene interface
 // Embed this in your scene to avoid implementing empty methods
 //
@@ -14,21 +23,28 @@ import (
 //   }
 //
 //   func (s *MyScene) OnEnter() {
-//       // Your setup code
+//       // Your setup code, registering systems to update each frame
+//       s.Systems.Add(engine.PhaseMovement, engine.NewMovementSystem(ents, tiles, 1))
 //   }
 //
-//   func (s *MyScene) Update(dt float64) Scene {
-//       // Your update code
-//       return nil
+//   func (s *MyScene) Update(dt float64) (Scene, error) {
+//       // Only needed to add to the default of updating Systems and then
+//       // running the callbacks added with OnUpdate
+//       return s.BaseScene.Update(dt)
 //   }
 //
 //   func (s *MyScene) Draw(screen *ebiten.Image) {
 //       // Your draw code
 //   }
 //
-// OnExit and SetViewport are already implemented (empty/storing viewport)
+// OnExit and SetViewport are already implemented (releasing tracked resources,
+// systems and callbacks, and storing viewport)
 type BaseScene struct {
-	Viewport geom.Size
+	Viewport  geom.Size
+	Game      *Game        // The game running this scene, set before OnEnter
+	Systems   SystemRunner // Systems the default Update runs, in phase order
+	resources []Deallocator
+	updates   []func(dt float64)
 }
 
 // OnEnter is called when the scene is loaded
@@ -36,20 +52,81 @@ type BaseScene struct {
 func (bs *BaseScene) OnEnter() {}
 
 // OnExit is called when the scene is removed
-// Override this to clean up resources
-func (bs *BaseScene) OnExit() {}
+// By default it deallocates resources registered with Track and removes the
+// Systems and OnUpdate callbacks, so an OnEnter that registers them runs the
+// same when the scene is entered again. If you override it call
+// bs.BaseScene.OnExit() from yours
+func (bs *BaseScene) OnExit() {
+	bs.ReleaseResources()
+	bs.Systems.Reset()
+	bs.updates = nil
+}
+
+// Track registers a resource created by the scene (e.g. its Assets or images it
+// made) to be deallocated when the scene exits. Tracking the same pointer more
+// than once only frees it once. Any Deallocator can be tracked, including ones
+// that aren't comparable
+func (bs *BaseScene) Track(r Deallocator) {
+	if slices.ContainsFunc(bs.resources, func(t Deallocator) bool { return sameResource(t, r) }) {
+		return
+	}
+	bs.resources = append(bs.resources, r)
+}
+
+// sameResource reports whether a and b are the same pointer. Only pointers are
+// compared as comparing other interface values can panic
+func sameResource(a, b Deallocator) bool {
+	if reflect.ValueOf(a).Kind() != reflect.Pointer || reflect.ValueOf(b).Kind() != reflect.Pointer {
+		return false
+	}
+	return a == b
+}
+
+// ReleaseResources deallocates all tracked resources and stops tracking them,
+// so re-entering the scene starts fresh
+func (bs *BaseScene) ReleaseResources() {
+	for _, r := range bs.resources {
+		r.Deallocate()
+	}
+	bs.resources = nil
+}
+
+// OnUpdate registers a callback to run every frame with dt, e.g. a spawn timer
+// or weather, without overriding Update. Callbacks run in the order they were
+// added, from RunUpdates
+func (bs *BaseScene) OnUpdate(fn func(dt float64)) {
+	bs.updates = append(bs.updates, fn)
+}
+
+// RunUpdates runs the callbacks registered with OnUpdate. The default Update
+// calls it after Systems, a scene overriding Update calls it wherever it wants
+// them to run relative to its systems (usually after them)
+func (bs *BaseScene) RunUpdates(dt float64) {
+	for _, fn := range bs.updates {
+		fn(dt)
+	}
+}
 
 // Update is called every frame
+// By default it updates Systems and then runs the OnUpdate callbacks
 // Override this to update your game logic
 // Return a new Scene to switch scenes, or nil to stay on this scene
-func (bs *BaseScene) Update(dt float64) Scene {
-	return nil
+func (bs *BaseScene) Update(dt float64) (Scene, error) {
+	bs.Systems.Update(dt)
+	bs.RunUpdates(dt)
+	return nil, nil
 }
 
 // Draw is called every frame to render
 // Override this to draw your scene
 func (bs *BaseScene) Draw(screen *ebiten.Image) {}
 
+// SetGame is called by the engine before OnEnter so the scene can reach the
+// Game (e.g. bs.Game.Freeze)
+func (bs *BaseScene) SetGame(g *Game) {
+	bs.Game = g
+}
+
 // SetViewport is called by the engine to set the viewport size
 // You usually don't need to override this - just access bs.Viewport
 func (bs *BaseScene) SetViewport(view geom.Size) {
@@ -0,0 +1,98 @@
+package engine
+
+import "testing"
+
+// countedRes counts its deallocations
+type countedRes struct{ freed int }
+
+func (r *countedRes) Deallocate() { r.freed++ }
+
+// funcRes is a Deallocator that isn't comparable
+type funcRes struct{ free func() }
+
+func (r funcRes) Deallocate() { r.free() }
+
+func TestBaseSceneReleasesTracked(t *testing.T) {
+	var bs BaseScene
+	shared := &countedRes{}
+	funcFreed := 0
+	bs.Track(shared)
+	bs.Track(shared)
+	bs.Track(funcRes{free: func() { funcFreed++ }})
+
+	bs.OnExit()
+	if shared.freed != 1 {
+		t.Errorf("resource tracked twice freed %d times, want 1", shared.freed)
+	}
+	if funcFreed != 1 {
+		t.Errorf("non-comparable resource freed %d times, want 1", funcFreed)
+	}
+
+	// Re-entering starts fresh
+	bs.OnExit()
+	if shared.freed != 1 {
+		t.Errorf("resource freed again by a second OnExit, %d times", shared.freed)
+	}
+	bs.Track(shared)
+	bs.OnExit()
+	if shared.freed != 2 {
+		t.Errorf("re-tracked resource freed %d times in total, want 2", shared.freed)
+	}
+}
+
+func TestBaseSceneOnUpdateRunsEachFrame(t *testing.T) {
+	var bs BaseScene
+	var order []string
+	var total float64
+	bs.Systems.Add(PhaseScripts, SystemFunc(func(float64) { order = append(order, "system") }))
+	bs.OnUpdate(func(dt float64) {
+		total += dt
+		order = append(order, "first")
+	})
+	bs.OnUpdate(func(float64) { order = append(order, "second") })
+
+	for range 3 {
+		if _, err := bs.Update(0.5); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if total != 1.5 {
+		t.Errorf("callback got %v s over 3 frames of 0.5s, want 1.5", total)
+	}
+	want := []string{"system", "first", "second"}
+	if len(order) != 3*len(want) {
+		t.Fatalf("ran %v, want %v each frame", order, want)
+	}
+	for i, name := range order {
+		if name != want[i%len(want)] {
+			t.Fatalf("ran %v, want %v each frame", order, want)
+		}
+	}
+}
+
+// enteringScene registers a system and a callback in OnEnter, like the examples
+type enteringScene struct {
+	BaseScene
+	systemRuns, callbackRuns int
+}
+
+func (s *enteringScene) OnEnter() {
+	s.Systems.Add(PhaseScripts, SystemFunc(func(float64) { s.systemRuns++ }))
+	s.OnUpdate(func(float64) { s.callbackRuns++ })
+}
+
+func TestBaseSceneReenterRegistersOnce(t *testing.T) {
+	s := &enteringScene{}
+	s.OnEnter()
+	s.OnExit()
+	s.OnEnter()
+
+	if _, err := s.Update(1.0 / 60); err != nil {
+		t.Fatal(err)
+	}
+	if s.systemRuns != 1 || s.callbackRuns != 1 {
+		t.Errorf("after entering twice one Update ran the system %d and the callback %d times, want once each",
+			s.systemRuns, s.callbackRuns)
+	}
+}
@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"github.com/samredway/ebx/assetmgr"
+	"github.com/samredway/ebx/geom"
+)
+
+// DebugState is a snapshot of the collision state of one frame, e.g. to save
+// as JSON and inspect in an external tool. See RenderSystem.DebugSnapshot
+type DebugState struct {
+	Camera     DebugCamera   `json:"camera"`
+	Entities   []DebugEntity `json:"entities"`
+	SolidTiles []geom.Vec2I  `json:"solidTiles"` // Solid cells in view (see CollisionGrid.IsSolid), by tile coords
+}
+
+// DebugCamera is the camera part of a DebugState
+type DebugCamera struct {
+	Pos  geom.Vec2 `json:"pos"`  // Top left of the view in world px
+	View geom.Rect `json:"view"` // World area in view
+	Zoom float64   `json:"zoom"`
+}
+
+// DebugEntity is an entity in a DebugState
+type DebugEntity struct {
+	Id   EntityId   `json:"id"`
+	Name string     `json:"name"`
+	Pos  geom.Vec2  `json:"pos"`
+	Box  *geom.Rect `json:"box,omitempty"` // Collision box in world px, nil if none or disabled
+	Dead bool       `json:"dead,omitempty"`
+}
+
+// DebugSnapshot captures every entity with a Position, its collision box, the
+// camera and the cells in view that grid says are solid, so it lists the same
+// tiles the MovementSystem stops at when given the grid. Everything is copied,
+// so take it between updates (e.g. from Draw) for a consistent single frame
+// that later frames don't change
+func (rs *RenderSystem) DebugSnapshot(grid *assetmgr.CollisionGrid) DebugState {
+	view := rs.camera.Viewport()
+	state := DebugState{
+		Camera: DebugCamera{
+			Pos: rs.camera.Vec2,
+			View: geom.Rect{
+				X: rs.camera.X,
+				Y: rs.camera.Y,
+				W: float64(view.W) / rs.camera.Zoom,
+				H: float64(view.H) / rs.camera.Zoom,
+			},
+			Zoom: rs.camera.Zoom,
+		},
+	}
+
+	rs.entities.Each(func(e *Entity) {
+		if e.Position == nil {
+			return
+		}
+		de := DebugEntity{Id: e.Id, Name: e.Name, Pos: e.Position.Vec2, Dead: e.Dead}
+		if box, ok := e.CollisionRect(); ok {
+			de.Box = &box
+		}
+		state.Entities = append(state.Entities, de)
+	})
+
+	cells := grid.Map().ClampRect(rs.VisibleTileRect())
+	for ty := cells.Min.Y; ty < cells.Max.Y; ty++ {
+		for tx := cells.Min.X; tx < cells.Max.X; tx++ {
+			if grid.IsSolid(tx, ty) {
+				state.SolidTiles = append(state.SolidTiles, geom.Vec2I{X: tx, Y: ty})
+			}
+		}
+	}
+	return state
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"encoding/json"
+	"image"
+	"slices"
+	"testing"
+
+	"github.com/samredway/ebx/assetmgr"
+	"github.com/samredway/ebx/camera"
+	"github.com/samredway/ebx/collision"
+	"github.com/samredway/ebx/geom"
+)
+
+func TestDebugSnapshot(t *testing.T) {
+	// Walls (1) round a room holding decoration (2) and a one-way platform (3)
+	tm, err := assetmgr.NewTileMap(6, 4, 16, 16, [][]int{{
+		1, 1, 1, 1, 1, 1,
+		1, 0, 2, 0, 0, 1,
+		1, 0, 0, 3, 0, 1,
+		1, 1, 1, 1, 1, 1,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm.SetTileBlock(3, collision.BlockNorth)
+	grid, err := assetmgr.NewCollisionGrid(tm, 0, func(id int) bool { return id != 2 })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ents := NewEntityManager()
+	player := mover(20, 20, 0, geom.Vec2I{})
+	player.Name = "player"
+	player.Collision.Offset = geom.Vec2{X: 2, Y: 4}
+	ghost := &Entity{Name: "ghost", Position: &PositionComponent{Vec2: geom.Vec2{X: 50, Y: 30}}}
+	ents.Add(player)
+	ents.Add(ghost)
+	ents.Add(&Entity{Name: "no position"})
+
+	cam := camera.NewCamera(geom.Size{W: 96, H: 64}, image.Rect(0, 0, 96, 64))
+	rs := NewRenderSystem(ents, cam, player, tm)
+	state := rs.DebugSnapshot(grid)
+
+	if len(state.Entities) != 2 {
+		t.Fatalf("snapshot has %d entities, want player and ghost: %+v", len(state.Entities), state.Entities)
+	}
+	p, g := state.Entities[0], state.Entities[1]
+	if p.Id != player.Id || p.Name != "player" || p.Pos != (geom.Vec2{X: 20, Y: 20}) {
+		t.Errorf("player = %+v", p)
+	}
+	if want := (geom.Rect{X: 22, Y: 24, W: 8, H: 8}); p.Box == nil || *p.Box != want {
+		t.Errorf("player box = %v, want %v", p.Box, want)
+	}
+	if g.Name != "ghost" || g.Box != nil {
+		t.Errorf("ghost = %+v, want no box", g)
+	}
+
+	// The whole map is in view. Decoration and one-way tiles aren't solid
+	var want []geom.Vec2I
+	for ty := range 4 {
+		for tx := range 6 {
+			if tx == 0 || ty == 0 || tx == 5 || ty == 3 {
+				want = append(want, geom.Vec2I{X: tx, Y: ty})
+			}
+		}
+	}
+	if !slices.Equal(state.SolidTiles, want) {
+		t.Errorf("SolidTiles = %v, want the walls %v", state.SolidTiles, want)
+	}
+
+	// Later frames don't change the snapshot
+	player.Position.X = 40
+	player.Collision.Offset.X = 0
+	if p := state.Entities[0]; p.Pos.X != 20 || p.Box.X != 22 {
+		t.Errorf("snapshot changed with the entity: %+v", p)
+	}
+
+	if _, err := json.Marshal(state); err != nil {
+		t.Errorf("snapshot doesn't marshal: %v", err)
+	}
+}
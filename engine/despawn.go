@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"github.com/samredway/ebx/camera"
+	"github.com/samredway/ebx/geom"
+)
+
+// DespawnSystem removes entities that stay far outside the camera view for a
+// while, e.g. enemies left behind on a large map, and brings them back when
+// the view returns to where they were. A despawned entity is taken out of the
+// EntityManager, so no system sees it, and marked Despawned for anything
+// holding on to it (e.g. a ChaseScript's Target). It is kept by the system
+// until it respawns. Dead is left alone, so death handling never mistakes a
+// despawn for a kill. Entities marked Persistent or Dead, and those without a
+// Position, are never despawned.
+//
+// Add it in PhaseCleanup after EntityManager.RemoveDead, so the frame's
+// systems have all run on entities it despawns and anything killed this frame
+// is removed as dead rather than kept to respawn
+type DespawnSystem struct {
+	entities *EntityManager
+	camera   *camera.Camera
+
+	Distance float64 // How far px outside the view counts as far (and how close brings it back)
+	Timeout  float64 // Seconds an entity must stay far before despawning
+
+	OnDespawn func(e *Entity) // Optional event, e.g. to save the entity's state
+	OnRespawn func(e *Entity) // Optional event, e.g. to reset its AI
+
+	farFor    map[*Entity]farTime // How long each live entity has been far
+	frame     uint64              // Count of Updates, to drop farFor entries for removed entities
+	despawned []*Entity
+}
+
+// farTime is how long an entity has been far, as of an Update
+type farTime struct {
+	secs  float64
+	frame uint64
+}
+
+func (ds *DespawnSystem) Update(dt float64) {
+	area := ds.area()
+
+	var gone []*Entity
+	ds.entities.Each(func(e *Entity) {
+		if e.Dead || e.Persistent || e.Position == nil {
+			delete(ds.farFor, e)
+			return
+		}
+		if area.Contains(e.Position.Vec2) {
+			delete(ds.farFor, e)
+			return
+		}
+		far := farTime{secs: ds.farFor[e].secs + dt, frame: ds.frame}
+		if far.secs < ds.Timeout {
+			ds.farFor[e] = far
+			return
+		}
+		delete(ds.farFor, e)
+		gone = append(gone, e)
+	})
+	// Forget entities removed from the manager since they went far
+	for e, far := range ds.farFor {
+		if far.frame != ds.frame {
+			delete(ds.farFor, e)
+		}
+	}
+	ds.frame++
+	for _, e := range gone {
+		ds.entities.remove(e)
+		e.Despawned = true
+		ds.despawned = append(ds.despawned, e)
+		if ds.OnDespawn != nil {
+			ds.OnDespawn(e)
+		}
+	}
+
+	waiting := ds.despawned[:0]
+	for _, e := range ds.despawned {
+		if !area.Contains(e.Position.Vec2) {
+			waiting = append(waiting, e)
+			continue
+		}
+		e.Despawned = false
+		ds.entities.Add(e)
+		if ds.OnRespawn != nil {
+			ds.OnRespawn(e)
+		}
+	}
+	clear(ds.despawned[len(waiting):])
+	ds.despawned = waiting
+}
+
+// Despawned returns the entities currently despawned, waiting to respawn
+func (ds *DespawnSystem) Despawned() []*Entity { return ds.despawned }
+
+// area returns the camera view grown by Distance on every side
+func (ds *DespawnSystem) area() geom.Rect {
+	view := ds.camera.Viewport()
+	return geom.Rect{
+		X: ds.camera.X - ds.Distance,
+		Y: ds.camera.Y - ds.Distance,
+		W: float64(view.W)/ds.camera.Zoom + 2*ds.Distance,
+		H: float64(view.H)/ds.camera.Zoom + 2*ds.Distance,
+	}
+}
+
+// NewDespawnSystem is constructor for DespawnSystem. Entities more than
+// distance px outside the view for timeout seconds are despawned
+func NewDespawnSystem(ents *EntityManager, cam *camera.Camera, distance, timeout float64) *DespawnSystem {
+	return &DespawnSystem{
+		entities: ents,
+		camera:   cam,
+		Distance: distance,
+		Timeout:  timeout,
+		farFor:   map[*Entity]farTime{},
+	}
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"image"
+	"slices"
+	"testing"
+
+	"github.com/samredway/ebx/camera"
+	"github.com/samredway/ebx/geom"
+)
+
+func TestDespawnFarEntities(t *testing.T) {
+	ents := NewEntityManager()
+	cam := camera.NewCamera(geom.Size{W: 96, H: 64}, image.Rect(0, 0, 1000, 1000))
+	near := &Entity{Name: "near", Position: &PositionComponent{Vec2: geom.Vec2{X: 40, Y: 30}}}
+	far := &Entity{Name: "far", Position: &PositionComponent{Vec2: geom.Vec2{X: 500, Y: 500}}}
+	player := &Entity{Name: "player", Persistent: true, Position: &PositionComponent{Vec2: geom.Vec2{X: 600, Y: 600}}}
+	for _, e := range []*Entity{near, far, player} {
+		ents.Add(e)
+	}
+	ds := NewDespawnSystem(ents, cam, 50, 1)
+	var despawns, respawns int
+	ds.OnDespawn = func(*Entity) { despawns++ }
+	ds.OnRespawn = func(*Entity) { respawns++ }
+
+	inManager := func(e *Entity) bool { return slices.Contains(ents.entities, e) }
+
+	ds.Update(0.6)
+	if far.Despawned || !inManager(far) {
+		t.Fatal("far entity despawned before the timeout")
+	}
+	ds.Update(0.6)
+	if !far.Despawned || inManager(far) {
+		t.Fatal("far entity not despawned after the timeout")
+	}
+	if far.Dead {
+		t.Error("despawned entity marked Dead")
+	}
+	if near.Despawned || !inManager(near) {
+		t.Error("near entity despawned")
+	}
+	if player.Despawned || !inManager(player) {
+		t.Error("persistent entity despawned")
+	}
+
+	// Returning to the area brings it back
+	cam.CentreOn(far.Position.Vec2)
+	ds.Update(0.1)
+	if far.Despawned || !inManager(far) {
+		t.Error("far entity not respawned when the view returned")
+	}
+	if despawns != 1 || respawns != 1 {
+		t.Errorf("OnDespawn called %d times, OnRespawn %d, want 1 each", despawns, respawns)
+	}
+}
@@ -0,0 +1,57 @@
+package engine
+
+import "github.com/samredway/ebx/geom"
+
+// FacingPolicy picks which axis wins when a diagonal FacingDir is reduced to
+// one of four directions, e.g. to choose a 4 direction animation
+type FacingPolicy int
+
+const (
+	FacingXPriority   FacingPolicy = iota // Up-right faces right
+	FacingYPriority                       // Up-right faces up
+	FacingLastChanged                     // The axis that changed most recently wins, e.g. the last key pressed
+)
+
+// Cardinal returns the facing direction as "down", "up", "right" or "left"
+// (see assetmgr.AnimationDirections), resolving diagonals with policy. No
+// facing yet gives "down"
+func (m *MovementComponent) Cardinal(policy FacingPolicy) string {
+	f := m.FacingDir
+	if f.X == 0 && f.Y == 0 {
+		return "down"
+	}
+	yWins := f.X == 0
+	if f.X != 0 && f.Y != 0 {
+		switch policy {
+		case FacingYPriority:
+			yWins = true
+		case FacingLastChanged:
+			// Both axes changing at once (e.g. from standing still) falls back
+			// to X priority
+			yWins = m.lastAxisY
+		}
+	}
+
+	switch {
+	case yWins && f.Y < 0:
+		return "up"
+	case yWins:
+		return "down"
+	case f.X < 0:
+		return "left"
+	default:
+		return "right"
+	}
+}
+
+// setFacing sets FacingDir and records which axis changed for FacingLastChanged
+func (m *MovementComponent) setFacing(dir geom.Vec2I) {
+	xChanged := dir.X != m.FacingDir.X
+	yChanged := dir.Y != m.FacingDir.Y
+	if xChanged != yChanged {
+		m.lastAxisY = yChanged
+	} else if xChanged {
+		m.lastAxisY = false
+	}
+	m.FacingDir = dir
+}
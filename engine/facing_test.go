@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/samredway/ebx/geom"
+)
+
+func TestCardinalUpRight(t *testing.T) {
+	upRight := geom.Vec2I{X: 1, Y: -1}
+	tests := []struct {
+		name   string
+		policy FacingPolicy
+		from   geom.Vec2I // Facing before turning up-right
+		want   string
+	}{
+		{"x priority", FacingXPriority, geom.Vec2I{Y: -1}, "right"},
+		{"y priority", FacingYPriority, geom.Vec2I{X: 1}, "up"},
+		{"last changed x", FacingLastChanged, geom.Vec2I{Y: -1}, "right"},
+		{"last changed y", FacingLastChanged, geom.Vec2I{X: 1}, "up"},
+		{"last changed both", FacingLastChanged, geom.Vec2I{}, "right"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m MovementComponent
+			m.setFacing(tt.from)
+			m.setFacing(upRight)
+			if got := m.Cardinal(tt.policy); got != tt.want {
+				t.Errorf("Cardinal = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// X priority stays the default policy
+	var zero FacingPolicy
+	if zero != FacingXPriority {
+		t.Errorf("zero FacingPolicy = %v, want FacingXPriority", zero)
+	}
+}
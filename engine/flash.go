@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// screenFlash is a full screen colour overlay fading out over its duration
+type screenFlash struct {
+	colour   color.Color
+	duration float64
+	elapsed  float64
+}
+
+// alpha returns how much of the flash colour is left, 1 fading to 0
+func (f *screenFlash) alpha() float64 {
+	return max(0, 1-f.elapsed/f.duration)
+}
+
+// FlashScreen fills the screen with c, fading out over duration seconds, e.g.
+// white for lightning or red when taking damage. Flashes started while others
+// are still fading are drawn over them so they blend together. By default the
+// flash is drawn at the end of Draw, over the world but under anything the
+// scene draws afterwards. Set FlashAfterUI to draw it with DrawFlashes instead
+func (rs *RenderSystem) FlashScreen(c color.Color, duration float64) {
+	if duration <= 0 {
+		return
+	}
+	rs.flashes = append(rs.flashes, &screenFlash{colour: c, duration: duration})
+}
+
+// DrawFlashes draws any active FlashScreen flashes. Draw calls it itself unless
+// FlashAfterUI is set, in which case call it after drawing the UI. Flashes fade
+// in the RenderSystem's Update. Does nothing when no flash is active
+func (rs *RenderSystem) DrawFlashes(screen *ebiten.Image) {
+	if len(rs.flashes) == 0 {
+		return
+	}
+
+	b := screen.Bounds()
+	for _, f := range rs.flashes {
+		a := f.alpha()
+		r, g, bl, al := f.colour.RGBA()
+		c := color.RGBA64{
+			R: uint16(float64(r) * a),
+			G: uint16(float64(g) * a),
+			B: uint16(float64(bl) * a),
+			A: uint16(float64(al) * a),
+		}
+		vector.FillRect(screen, float32(b.Min.X), float32(b.Min.Y), float32(b.Dx()), float32(b.Dy()), c, false)
+	}
+}
+
+// updateFlashes fades the active flashes by dt, dropping any that have ended
+func (rs *RenderSystem) updateFlashes(dt float64) {
+	active := rs.flashes[:0]
+	for _, f := range rs.flashes {
+		f.elapsed += dt
+		if f.elapsed < f.duration {
+			active = append(active, f)
+		}
+	}
+	clear(rs.flashes[len(active):])
+	rs.flashes = active
+}
@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestFlashDecay(t *testing.T) {
+	rs, _ := spriteScene(t)
+	screen := ebiten.NewImage(96, 64)
+
+	// Idle
+	rs.Update(0.1)
+	rs.DrawFlashes(screen)
+	if len(rs.flashes) != 0 {
+		t.Fatalf("%d flashes while idle", len(rs.flashes))
+	}
+
+	rs.FlashScreen(color.White, 1)
+	rs.FlashScreen(color.White, 0.5)
+	rs.Update(0.25)
+	if got := rs.flashes[0].alpha(); !near(got, 0.75) {
+		t.Errorf("1s flash alpha = %v after 0.25s, want 0.75", got)
+	}
+	if got := rs.flashes[1].alpha(); !near(got, 0.5) {
+		t.Errorf("0.5s flash alpha = %v after 0.25s, want 0.5", got)
+	}
+
+	// Drawing doesn't advance the fade
+	rs.Draw(screen)
+	rs.DrawFlashes(screen)
+	if got := rs.flashes[0].alpha(); !near(got, 0.75) {
+		t.Errorf("alpha = %v after drawing, want still 0.75", got)
+	}
+
+	rs.Update(0.25)
+	if len(rs.flashes) != 1 {
+		t.Fatalf("%d flashes after 0.5s, want the shorter one ended", len(rs.flashes))
+	}
+	rs.Update(0.5)
+	if len(rs.flashes) != 0 {
+		t.Errorf("%d flashes after 1s, want all ended", len(rs.flashes))
+	}
+}
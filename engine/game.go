@@ -1,19 +1,105 @@
 package engine
 
 import (
+	"fmt"
+	"image"
+	"math"
+	"slices"
+
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/samredway/ebx/assetmgr"
 	"github.com/samredway/ebx/geom"
 )
 
 // PositionComponent holds entity's position coords only
 type PositionComponent struct {
 	geom.Vec2 // X, Y
+
+	// PrevVec2 is the position before the last MovementSystem update, e.g. for
+	// render interpolation or rollback. Equal to the position until first moved
+	PrevVec2 geom.Vec2
+
+	// Snap opts the entity out of interpolation, so Interpolated always gives
+	// the current position, e.g. for a projectile that wraps around the screen.
+	// Use Teleport for a one off jump instead
+	Snap bool
+}
+
+// Interpolated returns the position alpha (0-1) of the way from PrevVec2 to
+// the current position, for drawing between fixed updates. With Snap set it
+// is the current position whatever alpha is
+func (p *PositionComponent) Interpolated(alpha float64) geom.Vec2 {
+	if p.Snap {
+		return p.Vec2
+	}
+	return geom.Vec2{
+		X: p.PrevVec2.X + (p.Vec2.X-p.PrevVec2.X)*alpha,
+		Y: p.PrevVec2.Y + (p.Vec2.Y-p.PrevVec2.Y)*alpha,
+	}
+}
+
+// Teleport moves straight to pos, also setting PrevVec2 so the jump isn't
+// interpolated across the screen, e.g. on respawn. It can be called at any
+// point in a frame
+func (p *PositionComponent) Teleport(pos geom.Vec2) {
+	p.Vec2 = pos
+	p.PrevVec2 = pos
 }
 
 // CollisionComponent holds collision shape data
 type CollisionComponent struct {
-	Size   geom.Size // Collision box dimensions
-	Offset geom.Vec2 // Offset from position (allows collision pos to be different to render)
+	Size   geom.Size // Collision box dimensions, zero = size of the render image
+	Offset geom.Vec2 // Offset from position, may be negative (allows collision pos to be different to render)
+
+	// AllowZeroSize keeps a zero Size as a zero size box (which never collides)
+	// rather than defaulting it to the render image size
+	AllowZeroSize bool
+
+	Response CollisionResponse // What happens on hitting a tile, default slide
+
+	// LayerMask lists the tile layers that block this entity, e.g. none for a
+	// ghost, or a land layer for a boat that can only go on water. nil uses
+	// the MovementSystem's collision layer. MovementSystem.SetSolid only
+	// applies to the collision layer, other layers block with every tile.
+	//
+	// An empty mask collides with no tile layers but is not "collide with
+	// nothing": the edges of the map still stop the entity so it can't leave
+	// the world. To turn collision off entirely, map edges included, set
+	// Disabled
+	LayerMask []int
+
+	// Mass weights how far the PushSystem moves the entity out of another,
+	// heavier entities move less. 0 counts as 1
+	Mass float64
+
+	// Immovable entities push others but are never pushed, e.g. the player
+	Immovable bool
+
+	// Disabled turns collision off, e.g. to phase through walls and enemies
+	// during a dash. When turned back on inside a wall the MovementSystem moves
+	// the entity to the nearest clear spot (see collision.Mover.NearestFree)
+	Disabled bool
+	phased   bool // was Disabled last MovementSystem update
+}
+
+// CollisionResponse is how the MovementSystem reacts to an entity hitting a tile
+type CollisionResponse int
+
+const (
+	CollisionSlide  CollisionResponse = iota // Stop on the blocked axis, keep moving along the other
+	CollisionBounce                          // Reflect DesiredDir (and fall speed) across the wall
+	CollisionStop                            // Stop dead and clear DesiredDir
+)
+
+// SensorComponent is a trigger area attached to an entity, e.g. an enemy's
+// detection range. It reports the entities whose collision boxes overlap it
+// (see SensorSystem) but never blocks movement, so an entity can have both a
+// solid CollisionComponent and a larger sensor
+type SensorComponent struct {
+	Size   geom.Size // Sensor box dimensions
+	Offset geom.Vec2 // Offset from position
+
+	Overlapping []*Entity // Entities overlapping the sensor as of the last SensorSystem update
 }
 
 // MovementComponent holds entity's movement state
@@ -22,13 +108,93 @@ type MovementComponent struct {
 	DesiredDir geom.Vec2I // Direction intent (-1, 0, 1) - set by input system
 	FacingDir  geom.Vec2I // Actual direction (-1, 0, 1) - set by movement system
 	IsMoving   bool       // Whether entity moved this frame - set by movement system
+	Velocity   geom.Vec2  // Actual px/s moved this frame after collision - set by movement system
+
+	// Only used when the movement system has gravity set (see MovementSystem.SetGravity)
+	Jump      bool    // Jump intent - set by input system, consumed by movement system
+	Grounded  bool    // Whether entity is standing on a tile - set by movement system
+	FallSpeed float64 // Vertical speed px/s, positive is down - set by movement system
+
+	lastAxisY bool       // Y was the last FacingDir axis to change (see FacingLastChanged)
+	blocked   geom.Vec2I // Directions blocked by a tile last update (see MovementSystem.IsBlocked)
 }
 
 // RenderComponent holds current image
 type RenderComponent struct {
-	Img *ebiten.Image
+	Img       *ebiten.Image
+	FadeFrom  *ebiten.Image // Optional outgoing image drawn while crossfading (see Crossfade)
+	FadeAlpha float64       // Opacity of Img while FadeFrom is set, FadeFrom gets 1-FadeAlpha
+
+	// SrcRect optionally draws only this region of Img, relative to its top left
+	// corner, e.g. the filled part of a progress bar. The zero rect draws the
+	// whole image, any other empty rect (a 0% full bar) draws nothing
+	SrcRect image.Rectangle
+
+	// Offset nudges where Img is drawn from the entity position, in source
+	// pixels (scaled by zoom like the sprite), e.g. set per animation frame for
+	// a sword thrust lunging forward. Collision and the shadow are not moved
+	Offset geom.Vec2
+
+	// FlipX mirrors Img left to right in place, e.g. to reuse right facing
+	// frames for facing left. Offset.X is mirrored with it, about the centre
+	// of the collision box
+	FlipX bool
+
+	Shadow *Shadow // Optional blob shadow drawn under the sprite, nil = off
 }
 
+// Shadow is a translucent ellipse drawn beneath a sprite
+type Shadow struct {
+	Size   geom.Size // Ellipse width and height px
+	Offset geom.Vec2 // Moves the shadow from its default spot at the sprite's feet
+	Alpha  float64   // Opacity 0-1
+}
+
+// Rect returns the bounds of the shadow ellipse in world coords for a sprite of
+// spriteSize drawn at pos. The shadow is centred on the bottom edge of the
+// sprite (its feet) and then moved by Offset
+func (sh *Shadow) Rect(pos geom.Vec2, spriteSize geom.Size) geom.Rect {
+	w, h := float64(sh.Size.W), float64(sh.Size.H)
+	cx := pos.X + float64(spriteSize.W)/2 + sh.Offset.X
+	cy := pos.Y + float64(spriteSize.H) + sh.Offset.Y
+	return geom.Rect{X: cx - w/2, Y: cy - h/2, W: w, H: h}
+}
+
+// crop returns the region of img selected by SrcRect. ok is false if SrcRect
+// is set but selects nothing, so there is nothing to draw
+func (r *RenderComponent) crop(img *ebiten.Image) (cropped *ebiten.Image, ok bool) {
+	if r.SrcRect == (image.Rectangle{}) {
+		return img, true
+	}
+	src := r.SrcRect.Add(img.Bounds().Min).Intersect(img.Bounds())
+	if src.Empty() {
+		return nil, false
+	}
+	return img.SubImage(src).(*ebiten.Image), true
+}
+
+// ItemComponent marks an entity as an item that can be picked up
+type ItemComponent struct {
+	ID    string // Item type, items with the same ID stack
+	Count int    // Stack size, 0 counts as 1
+}
+
+// InventoryComponent holds picked up item counts by item ID
+type InventoryComponent struct {
+	Items map[string]int
+}
+
+// Add adds count of an item to the inventory
+func (inv *InventoryComponent) Add(id string, count int) {
+	if inv.Items == nil {
+		inv.Items = map[string]int{}
+	}
+	inv.Items[id] += count
+}
+
+// Count returns how many of an item are held
+func (inv *InventoryComponent) Count(id string) int { return inv.Items[id] }
+
 // Used to give entity specific custom behaviour to manage stuff like animations
 // inputs/AI etc
 type Script interface {
@@ -37,22 +203,151 @@ type Script interface {
 
 // Entity game entity type
 type Entity struct {
+	Id        EntityId // Set by EntityManager.Add if 0
 	Name      string
 	Position  *PositionComponent
 	Movement  *MovementComponent
 	Render    *RenderComponent
 	Collision *CollisionComponent
+	Sensor    *SensorComponent
+	Item      *ItemComponent
+	Inventory *InventoryComponent
+	AI        *AIComponent
+	Parent    *ParentComponent
 	Script    Script
 	Dead      bool
+
+	Persistent bool // Never despawned by the DespawnSystem, e.g. the player
+	Despawned  bool // Removed by the DespawnSystem until it respawns, unlike Dead it isn't gone for good
+}
+
+// CollisionSize returns the size of the entity's collision box. A zero Size
+// falls back to the render image size (unless AllowZeroSize is set) so an
+// entity that forgot to set it doesn't silently walk through walls
+func (e *Entity) CollisionSize() geom.Size {
+	c := e.Collision
+	if c == nil {
+		return geom.Size{}
+	}
+	if c.Size != (geom.Size{}) || c.AllowZeroSize || e.Render == nil || e.Render.Img == nil {
+		return c.Size
+	}
+	b := e.Render.Img.Bounds()
+	return geom.Size{W: b.Dx(), H: b.Dy()}
+}
+
+// CollisionRect returns the entity's collision box in world coords. ok is false
+// if the entity has no Position or Collision component or collision is Disabled
+func (e *Entity) CollisionRect() (rect geom.Rect, ok bool) {
+	if e.Position == nil || e.Collision == nil || e.Collision.Disabled {
+		return geom.Rect{}, false
+	}
+	size := e.CollisionSize()
+	return geom.Rect{
+		X: e.Position.X + e.Collision.Offset.X,
+		Y: e.Position.Y + e.Collision.Offset.Y,
+		W: float64(size.W),
+		H: float64(size.H),
+	}, true
+}
+
+// CollisionIssues checks the entity's collision box for likely setup mistakes:
+// a zero or negative size (unless AllowZeroSize is set) or a box reaching
+// outside the render image. It is advisory only, returning a description of
+// each issue found (none if the entity has no Collision component)
+func (e *Entity) CollisionIssues() []string {
+	c := e.Collision
+	if c == nil {
+		return nil
+	}
+
+	var issues []string
+	size := e.CollisionSize()
+	switch {
+	case size.W < 0 || size.H < 0:
+		issues = append(issues, fmt.Sprintf("%s: negative collision size %dx%d", e.Name, size.W, size.H))
+	case (size.W == 0 || size.H == 0) && !c.AllowZeroSize:
+		issues = append(issues, fmt.Sprintf("%s: zero collision size %dx%d never collides", e.Name, size.W, size.H))
+	}
+
+	if e.Render != nil && e.Render.Img != nil {
+		b := e.Render.Img.Bounds()
+		if c.Offset.X < 0 || c.Offset.Y < 0 ||
+			c.Offset.X+float64(size.W) > float64(b.Dx()) ||
+			c.Offset.Y+float64(size.H) > float64(b.Dy()) {
+			issues = append(issues, fmt.Sprintf(
+				"%s: collision box %dx%d at offset %v,%v extends outside the %dx%d render image",
+				e.Name, size.W, size.H, c.Offset.X, c.Offset.Y, b.Dx(), b.Dy(),
+			))
+		}
+	}
+	return issues
+}
+
+// SensorRect returns the entity's sensor box in world coords. ok is false if
+// the entity has no Position or Sensor component
+func (e *Entity) SensorRect() (rect geom.Rect, ok bool) {
+	if e.Position == nil || e.Sensor == nil {
+		return geom.Rect{}, false
+	}
+	return geom.Rect{
+		X: e.Position.X + e.Sensor.Offset.X,
+		Y: e.Position.Y + e.Sensor.Offset.Y,
+		W: float64(e.Sensor.Size.W),
+		H: float64(e.Sensor.Size.H),
+	}, true
+}
+
+// TilesUnder returns the distinct non-zero tile IDs the entity's collision box
+// overlaps in a layer of tm, e.g. a damage layer of spikes. Nothing is blocked
+// or resolved, so a system can apply a different effect per tile ID
+func (e *Entity) TilesUnder(tm *assetmgr.TileMap, layer int) ([]int, error) {
+	rect, ok := e.CollisionRect()
+	if !ok {
+		return nil, nil
+	}
+	return tm.OverlappedTileIds(rect.X, rect.Y, rect.W, rect.H, layer)
+}
+
+// EntitiesOverlap reports whether the collision boxes of a and b overlap right
+// now, e.g. the player touching a boss. It is false if either has no Position
+// or Collision component or has collision Disabled (see CollisionRect)
+func EntitiesOverlap(a, b *Entity) bool {
+	boxA, ok := a.CollisionRect()
+	if !ok {
+		return false
+	}
+	boxB, ok := b.CollisionRect()
+	return ok && boxA.Overlaps(boxB)
 }
 
 // EntityManager is a deliberately small abstraction to handle game entities
 type EntityManager struct {
 	entities []*Entity
+	ids      IdGen
+
+	// OnCollisionIssues, if set, is called by Add with any problems found by
+	// Entity.CollisionIssues, e.g. to log them while developing
+	OnCollisionIssues func(e *Entity, issues []string)
 }
 
-// Add adds new entity
+// Add adds new entity, giving it an Id if it doesn't have one. Entities
+// added with their own Id (e.g. loaded from a save) are reserved so new ids
+// never clash with them
 func (em *EntityManager) Add(e *Entity) {
+	if e.Id == 0 {
+		e.Id = em.ids.Next()
+	} else {
+		em.ids.Reserve(e.Id)
+	}
+	if e.Position != nil {
+		e.Position.PrevVec2 = e.Position.Vec2
+	}
+	if em.OnCollisionIssues != nil {
+		if issues := e.CollisionIssues(); len(issues) > 0 {
+			em.OnCollisionIssues(e, issues)
+		}
+	}
 	em.entities = append(em.entities, e)
 }
 
@@ -63,6 +358,29 @@ func (em *EntityManager) Each(fn func(*Entity)) {
 	}
 }
 
+// remove removes e from the manager. It must not be called from inside Each
+func (em *EntityManager) remove(e *Entity) {
+	if i := slices.Index(em.entities, e); i >= 0 {
+		em.entities = slices.Delete(em.entities, i, i+1)
+	}
+}
+
+// QueryRect returns the live entities whose collision box overlaps rect, e.g.
+// everything caught in an explosion. Entities without a CollisionComponent
+// are never included
+func (em *EntityManager) QueryRect(rect geom.Rect) []*Entity {
+	var found []*Entity
+	em.Each(func(e *Entity) {
+		if e.Dead {
+			return
+		}
+		if box, ok := e.CollisionRect(); ok && box.Overlaps(rect) {
+			found = append(found, e)
+		}
+	})
+	return found
+}
+
 func (em *EntityManager) Update(dt float64) {
 	em.Each(func(e *Entity) {
 		if e.Script != nil {
@@ -97,40 +415,133 @@ type Scene interface {
 	SetViewport(geom.Size)
 }
 
+// LayoutMode decides how the logical screen size is derived from the window
+// size. In every mode the scene's viewport is kept equal to the logical size
+type LayoutMode int
+
+const (
+	// LayoutFixed always uses the resolution passed to NewGame and lets Ebiten
+	// scale it to fit the window (letterboxed). This is the default
+	LayoutFixed LayoutMode = iota
+	// LayoutStretch scales the resolution by the largest factor that fits the
+	// window and grows the logical screen on the other axis to fill it
+	LayoutStretch
+	// LayoutIntegerScale is like LayoutStretch but only scales by whole numbers
+	// so pixel art stays pixel perfect
+	LayoutIntegerScale
+)
+
+// LayoutSize returns the logical screen size for a window of size outside,
+// given the logical resolution the game was designed for
+func LayoutSize(mode LayoutMode, resolution, outside geom.Size) geom.Size {
+	if mode == LayoutFixed || outside.W <= 0 || outside.H <= 0 {
+		return resolution
+	}
+
+	scale := math.Min(
+		float64(outside.W)/float64(resolution.W),
+		float64(outside.H)/float64(resolution.H),
+	)
+	if mode == LayoutIntegerScale {
+		scale = math.Floor(scale)
+	}
+	// Window is smaller than the resolution so let Ebiten shrink it instead
+	if scale < 1 {
+		return resolution
+	}
+
+	return geom.Size{
+		W: int(float64(outside.W) / scale),
+		H: int(float64(outside.H) / scale),
+	}
+}
+
+// GameAware is optionally implemented by a Scene to be given the Game running
+// it, e.g. to call Freeze. BaseScene implements it
+type GameAware interface {
+	SetGame(*Game)
+}
+
 // Game object implements ebiten.Game interface
 type Game struct {
-	curr     Scene
-	viewport geom.Size
+	curr       Scene
+	viewport   geom.Size // current logical screen size
+	resolution geom.Size // logical resolution requested in NewGame
+	layout     LayoutMode
+	freeze     int     // frames of Update left to skip, see Freeze
+	elapsed    float64 // seconds of scene updates run, see Elapsed
 }
 
 func (g *Game) Update() error {
+	if g.freeze > 0 {
+		g.freeze--
+		return nil
+	}
+
 	fps := float64(ebiten.TPS())
 	dt := 1 / fps
+	g.elapsed += dt
 	scene, err := g.curr.Update(dt)
 	if scene != nil {
 		g.curr.OnExit()
-		g.curr = scene
-		g.curr.OnEnter()
+		g.enter(scene)
 	}
 	return err
 }
 
+// Freeze pauses scene updates for the given number of frames while drawing
+// carries on, for a hit-stop effect on impactful hits. Everything driven by
+// the scene's Update (scripts, movement, animation) halts together.
+// Freezing while already frozen keeps whichever freeze ends later
+func (g *Game) Freeze(frames int) {
+	g.freeze = max(g.freeze, frames)
+}
+
+// Elapsed returns the total seconds of game time so far, the sum of every dt
+// passed to a scene's Update. Freeze is the only pause the Game has, frozen
+// frames don't count, so it suits day/night cycles and timed events that
+// should stop with the game. A scene pausing itself (e.g. a pause menu) is
+// still updated so its time still counts
+func (g *Game) Elapsed() float64 { return g.elapsed }
+
+// enter makes scene the current scene and starts it
+func (g *Game) enter(scene Scene) {
+	g.curr = scene
+	if ga, ok := scene.(GameAware); ok {
+		ga.SetGame(g)
+	}
+	g.curr.SetViewport(g.viewport)
+	g.curr.OnEnter()
+}
+
 func (g *Game) Draw(screen *ebiten.Image) {
 	g.curr.Draw(screen)
 }
 
+// Layout computes the logical screen size according to the LayoutMode and
+// passes any change on to the current scene via SetViewport
 func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	size := LayoutSize(g.layout, g.resolution, geom.Size{W: outsideWidth, H: outsideHeight})
+	if size != g.viewport {
+		g.viewport = size
+		g.curr.SetViewport(size)
+	}
 	return g.viewport.W, g.viewport.H
 }
 
+// SetLayoutMode changes how the window size maps to the logical screen size
+func (g *Game) SetLayoutMode(mode LayoutMode) {
+	g.layout = mode
+}
+
 // NewGame returns a Game object that can run in Ebiten.
 // You must pass in a Scene argument that is your opening scene along with
 // an Assets object which contains all the assets your game requires
 func NewGame(scene Scene, viewport geom.Size) *Game {
-	scene.SetViewport(viewport)
-	scene.OnEnter()
-	return &Game{
-		curr:     scene,
-		viewport: viewport,
+	g := &Game{
+		viewport:   viewport,
+		resolution: viewport,
 	}
+	g.enter(scene)
+	return g
 }
@@ -0,0 +1,458 @@
+package engine
+
+import (
+	"image"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/samredway/ebx/assetmgr"
+	"github.com/samredway/ebx/geom"
+)
+
+func TestLayoutSize(t *testing.T) {
+	res := geom.Size{W: 320, H: 180}
+	tests := []struct {
+		name    string
+		mode    LayoutMode
+		outside geom.Size
+		want    geom.Size
+	}{
+		{"fixed ignores window", LayoutFixed, geom.Size{W: 1920, H: 1200}, res},
+		{"stretch exact multiple", LayoutStretch, geom.Size{W: 1280, H: 720}, res},
+		{"stretch grows taller axis", LayoutStretch, geom.Size{W: 1280, H: 800}, geom.Size{W: 320, H: 200}},
+		{"stretch fractional scale", LayoutStretch, geom.Size{W: 800, H: 450}, geom.Size{W: 320, H: 180}},
+		{"integer floors scale", LayoutIntegerScale, geom.Size{W: 1000, H: 600}, geom.Size{W: 333, H: 200}},
+		{"integer exact multiple", LayoutIntegerScale, geom.Size{W: 960, H: 540}, res},
+		{"smaller window keeps resolution", LayoutStretch, geom.Size{W: 160, H: 90}, res},
+		{"smaller window keeps resolution integer", LayoutIntegerScale, geom.Size{W: 400, H: 200}, geom.Size{W: 400, H: 200}},
+		{"zero window", LayoutIntegerScale, geom.Size{}, res},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := LayoutSize(tt.mode, res, tt.outside)
+			if got != tt.want {
+				t.Errorf("LayoutSize(%v, %v, %v) = %v, want %v", tt.mode, res, tt.outside, got, tt.want)
+			}
+		})
+	}
+}
+
+// viewportScene records the viewport it is given
+type viewportScene struct {
+	viewport geom.Size
+}
+
+func (s *viewportScene) OnEnter()                      {}
+func (s *viewportScene) OnExit()                       {}
+func (s *viewportScene) Draw(*ebiten.Image)            {}
+func (s *viewportScene) Update(float64) (Scene, error) { return nil, nil }
+func (s *viewportScene) SetViewport(size geom.Size)    { s.viewport = size }
+
+func TestGameLayoutUpdatesSceneViewport(t *testing.T) {
+	scene := &viewportScene{}
+	g := NewGame(scene, geom.Size{W: 320, H: 180})
+	g.SetLayoutMode(LayoutStretch)
+
+	w, h := g.Layout(1280, 800)
+	if w != 320 || h != 200 {
+		t.Fatalf("Layout = %d, %d, want 320, 200", w, h)
+	}
+	if scene.viewport != (geom.Size{W: 320, H: 200}) {
+		t.Errorf("scene viewport = %v, want the layout size 320x200", scene.viewport)
+	}
+}
+
+func TestRenderCrop(t *testing.T) {
+	img := ebiten.NewImage(32, 8)
+
+	tests := []struct {
+		name   string
+		src    image.Rectangle
+		want   image.Rectangle
+		wantOk bool
+	}{
+		{"unset draws whole image", image.Rectangle{}, image.Rect(0, 0, 32, 8), true},
+		{"part", image.Rect(0, 0, 12, 8), image.Rect(0, 0, 12, 8), true},
+		{"empty draws nothing", image.Rect(0, 0, 0, 8), image.Rectangle{}, false},
+		{"outside the image draws nothing", image.Rect(40, 0, 50, 8), image.Rectangle{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &RenderComponent{Img: img, SrcRect: tt.src}
+			got, ok := r.crop(img)
+			if ok != tt.wantOk {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && got.Bounds() != tt.want {
+				t.Errorf("bounds = %v, want %v", got.Bounds(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEntitiesOverlap(t *testing.T) {
+	box := func(x, y float64, offset geom.Vec2) *Entity {
+		return &Entity{
+			Position:  &PositionComponent{Vec2: geom.Vec2{X: x, Y: y}},
+			Collision: &CollisionComponent{Size: geom.Size{W: 16, H: 16}, Offset: offset},
+		}
+	}
+	disabled := box(0, 0, geom.Vec2{})
+	disabled.Collision.Disabled = true
+	sized := &Entity{
+		Position:  &PositionComponent{Vec2: geom.Vec2{X: 8, Y: 8}},
+		Collision: &CollisionComponent{},
+		Render:    &RenderComponent{Img: ebiten.NewImage(16, 16)},
+	}
+
+	tests := []struct {
+		name string
+		a, b *Entity
+		want bool
+	}{
+		{"overlapping", box(0, 0, geom.Vec2{}), box(8, 8, geom.Vec2{}), true},
+		{"apart", box(0, 0, geom.Vec2{}), box(32, 0, geom.Vec2{}), false},
+		{"touching edges", box(0, 0, geom.Vec2{}), box(16, 0, geom.Vec2{}), false},
+		{"offset into overlap", box(0, 0, geom.Vec2{}), box(20, 0, geom.Vec2{X: -8}), true},
+		{"offset out of overlap", box(0, 0, geom.Vec2{}), box(8, 0, geom.Vec2{X: 12}), false},
+		{"size from render image", box(0, 0, geom.Vec2{}), sized, true},
+		{"no collision component", box(0, 0, geom.Vec2{}), &Entity{Position: &PositionComponent{}}, false},
+		{"no position", box(0, 0, geom.Vec2{}), &Entity{Collision: &CollisionComponent{Size: geom.Size{W: 16, H: 16}}}, false},
+		{"disabled", box(0, 0, geom.Vec2{}), disabled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EntitiesOverlap(tt.a, tt.b); got != tt.want {
+				t.Errorf("EntitiesOverlap = %v, want %v", got, tt.want)
+			}
+			if got := EntitiesOverlap(tt.b, tt.a); got != tt.want {
+				t.Errorf("EntitiesOverlap swapped = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryRect(t *testing.T) {
+	ents := NewEntityManager()
+	at := func(name string, x, y float64) *Entity {
+		e := mover(x, y, 0, geom.Vec2I{})
+		e.Name = name
+		ents.Add(e)
+		return e
+	}
+	inside := at("inside", 20, 20)
+	overlapping := at("overlapping", 36, 36)
+	at("touching right edge", 40, 20)
+	at("touching bottom edge", 20, 40)
+	at("outside", 100, 100)
+	dead := at("dead", 24, 24)
+	dead.Dead = true
+	ents.Add(&Entity{Name: "no collision", Position: &PositionComponent{Vec2: geom.Vec2{X: 24, Y: 24}}})
+
+	found := ents.QueryRect(geom.Rect{X: 16, Y: 16, W: 24, H: 24})
+	var names []string
+	for _, e := range found {
+		names = append(names, e.Name)
+	}
+	if len(found) != 2 || found[0] != inside || found[1] != overlapping {
+		t.Errorf("QueryRect found %v, want [inside overlapping]", names)
+	}
+}
+
+// countingScene counts its Update and Draw calls
+type countingScene struct {
+	updates, draws int
+}
+
+func (s *countingScene) OnEnter()                      {}
+func (s *countingScene) OnExit()                       {}
+func (s *countingScene) Draw(*ebiten.Image)            { s.draws++ }
+func (s *countingScene) Update(float64) (Scene, error) { s.updates++; return nil, nil }
+func (s *countingScene) SetViewport(geom.Size)         {}
+
+func TestGameFreeze(t *testing.T) {
+	scene := &countingScene{}
+	g := NewGame(scene, geom.Size{W: 320, H: 180})
+	screen := ebiten.NewImage(320, 180)
+	frame := func() {
+		if err := g.Update(); err != nil {
+			t.Fatal(err)
+		}
+		g.Draw(screen)
+	}
+
+	g.Freeze(3)
+	for range 3 {
+		frame()
+	}
+	if scene.updates != 0 || scene.draws != 3 {
+		t.Errorf("during a 3 frame freeze %d updates and %d draws, want 0 and 3", scene.updates, scene.draws)
+	}
+	frame()
+	if scene.updates != 1 {
+		t.Errorf("%d updates the frame after the freeze, want 1", scene.updates)
+	}
+
+	// A shorter freeze during a longer one keeps the longer end
+	scene.updates = 0
+	g.Freeze(5)
+	frame()
+	g.Freeze(2)
+	for range 4 {
+		frame()
+	}
+	if scene.updates != 0 {
+		t.Errorf("%d updates in a 5 frame freeze overlapped by a 2 frame one, want 0", scene.updates)
+	}
+	frame()
+	if scene.updates != 1 {
+		t.Errorf("%d updates after the longer freeze ended, want 1", scene.updates)
+	}
+
+	// A longer freeze during a shorter one extends it
+	scene.updates = 0
+	g.Freeze(2)
+	frame()
+	g.Freeze(4)
+	for range 4 {
+		frame()
+	}
+	if scene.updates != 0 {
+		t.Errorf("%d updates during the extended freeze, want 0", scene.updates)
+	}
+}
+
+func TestGameElapsed(t *testing.T) {
+	g := NewGame(&countingScene{}, geom.Size{W: 320, H: 180})
+	dt := 1 / float64(ebiten.TPS())
+
+	for range 10 {
+		g.Update()
+	}
+	if got := g.Elapsed(); !near(got, 10*dt) {
+		t.Errorf("Elapsed after 10 updates = %v, want %v", got, 10*dt)
+	}
+
+	// Halts while frozen
+	g.Freeze(5)
+	for range 5 {
+		g.Update()
+	}
+	if got := g.Elapsed(); !near(got, 10*dt) {
+		t.Errorf("Elapsed after 5 frozen frames = %v, want unchanged at %v", got, 10*dt)
+	}
+
+	g.Update()
+	if got := g.Elapsed(); !near(got, 11*dt) {
+		t.Errorf("Elapsed after the freeze = %v, want %v", got, 11*dt)
+	}
+}
+
+func TestCollisionSizeDefaultsToImage(t *testing.T) {
+	img := ebiten.NewImage(12, 10)
+	e := &Entity{
+		Position:  &PositionComponent{Vec2: geom.Vec2{X: 40, Y: 20}},
+		Movement:  &MovementComponent{Speed: 120, DesiredDir: geom.Vec2I{X: 1}},
+		Collision: &CollisionComponent{},
+		Render:    &RenderComponent{Img: img},
+	}
+	if got, want := e.CollisionSize(), (geom.Size{W: 12, H: 10}); got != want {
+		t.Fatalf("zero Size gave %v, want the image size %v", got, want)
+	}
+
+	// The defaulted box collides with the right wall at 80
+	ents := NewEntityManager()
+	ents.Add(e)
+	ms := NewMovementSystem(ents, roomMap(t), 0)
+	for range 60 {
+		ms.Update(1.0 / 60)
+	}
+	if got := e.Position.X; got > 80-12 || got < 80-12-0.01 {
+		t.Errorf("stopped at X %v, want against the wall at %v", got, 80-12)
+	}
+
+	// An explicit zero stays zero
+	e.Collision.AllowZeroSize = true
+	if got := e.CollisionSize(); got != (geom.Size{}) {
+		t.Errorf("AllowZeroSize gave %v, want zero", got)
+	}
+	if box, _ := e.CollisionRect(); box.Overlaps(geom.Rect{X: 0, Y: 0, W: 1000, H: 1000}) {
+		t.Error("zero size box overlaps a rect around it")
+	}
+	ms.Update(1.0 / 60)
+	if e.Position.X <= 80-12 {
+		t.Errorf("zero size box stopped at X %v, want it to pass into the wall", e.Position.X)
+	}
+
+	// A set Size is used over the image
+	e.Collision.Size = geom.Size{W: 4, H: 6}
+	if got := e.CollisionSize(); got != e.Collision.Size {
+		t.Errorf("set Size gave %v, want %v", got, e.Collision.Size)
+	}
+}
+
+func TestCollisionIssues(t *testing.T) {
+	img := ebiten.NewImage(16, 16)
+	tests := []struct {
+		name      string
+		collision *CollisionComponent
+		render    *RenderComponent
+		want      int // Number of issues
+		contains  string
+	}{
+		{"fits the image", &CollisionComponent{Size: geom.Size{W: 12, H: 8}, Offset: geom.Vec2{X: 2, Y: 8}}, &RenderComponent{Img: img}, 0, ""},
+		{"defaults to the image", &CollisionComponent{}, &RenderComponent{Img: img}, 0, ""},
+		{"no collision", nil, &RenderComponent{Img: img}, 0, ""},
+		{"zero size", &CollisionComponent{}, nil, 1, "zero collision size"},
+		{"allowed zero size", &CollisionComponent{AllowZeroSize: true}, &RenderComponent{Img: img}, 0, ""},
+		{"negative size", &CollisionComponent{Size: geom.Size{W: -4, H: 8}}, nil, 1, "negative"},
+		{"larger than the image", &CollisionComponent{Size: geom.Size{W: 32, H: 16}}, &RenderComponent{Img: img}, 1, "outside the 16x16"},
+		{"offset out of the image", &CollisionComponent{Size: geom.Size{W: 8, H: 8}, Offset: geom.Vec2{X: -2}}, &RenderComponent{Img: img}, 1, "outside"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Entity{Name: "slime", Collision: tt.collision, Render: tt.render}
+			issues := e.CollisionIssues()
+			if len(issues) != tt.want {
+				t.Fatalf("issues %q, want %d", issues, tt.want)
+			}
+			if tt.want > 0 && !strings.Contains(issues[0], tt.contains) {
+				t.Errorf("issue %q doesn't mention %q", issues[0], tt.contains)
+			}
+			if tt.want > 0 && !strings.HasPrefix(issues[0], "slime: ") {
+				t.Errorf("issue %q doesn't name the entity", issues[0])
+			}
+		})
+	}
+}
+
+func TestOnCollisionIssuesHook(t *testing.T) {
+	ents := NewEntityManager()
+	ents.Add(&Entity{Name: "before the hook", Collision: &CollisionComponent{}})
+
+	var reported []string
+	ents.OnCollisionIssues = func(e *Entity, issues []string) {
+		reported = append(reported, e.Name)
+	}
+	ents.Add(&Entity{Name: "fine", Collision: &CollisionComponent{Size: geom.Size{W: 8, H: 8}}})
+	ents.Add(&Entity{Name: "zero", Collision: &CollisionComponent{}})
+	ents.Add(&Entity{Name: "oversized", Collision: &CollisionComponent{Size: geom.Size{W: 32, H: 32}},
+		Render: &RenderComponent{Img: ebiten.NewImage(16, 16)}})
+
+	if !slices.Equal(reported, []string{"zero", "oversized"}) {
+		t.Errorf("hook called for %v, want [zero oversized]", reported)
+	}
+	if len(ents.entities) != 4 {
+		t.Errorf("%d entities added, want all 4 as issues are advisory", len(ents.entities))
+	}
+}
+
+func TestTilesUnderDamageLayer(t *testing.T) {
+	// Layer 1 holds spikes (5) and lava (7) on the floor of the room
+	tm, err := assetmgr.NewTileMap(6, 4, 16, 16, [][]int{
+		roomMap(t).Layers[0],
+		{
+			0, 0, 0, 0, 0, 0,
+			0, 0, 0, 0, 0, 0,
+			0, 0, 5, 7, 0, 0,
+			0, 0, 0, 0, 0, 0,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		x    float64
+		want []int
+	}{
+		{"on spikes", 36, []int{5}},
+		{"on spikes and lava", 44, []int{5, 7}},
+		{"on the floor", 20, nil},
+		{"touching the spikes' edge", 24, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := mover(tt.x, 36, 0, geom.Vec2I{})
+			got, err := e.TilesUnder(tm, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("TilesUnder = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// The damage layer doesn't block movement in the collision layer
+	ents := NewEntityManager()
+	e := mover(20, 36, 120, geom.Vec2I{X: 1})
+	ents.Add(e)
+	ms := NewMovementSystem(ents, tm, 0)
+	for range 20 {
+		ms.Update(1.0 / 60)
+	}
+	if !near(e.Position.X, 60) {
+		t.Errorf("at X %v walking over the damage tiles, want 60", e.Position.X)
+	}
+}
+
+func TestPrevVec2AndInterpolated(t *testing.T) {
+	ents := NewEntityManager()
+	e := mover(20, 20, 60, geom.Vec2I{X: 1})
+	ents.Add(e)
+	if e.Position.PrevVec2 != e.Position.Vec2 {
+		t.Fatalf("PrevVec2 %v on the first frame, want the position %v", e.Position.PrevVec2, e.Position.Vec2)
+	}
+	ms := NewMovementSystem(ents, roomMap(t), 0)
+
+	for range 3 {
+		before := e.Position.Vec2
+		ms.Update(1.0 / 60)
+		if e.Position.PrevVec2 != before {
+			t.Errorf("PrevVec2 = %v, want the position before the update %v", e.Position.PrevVec2, before)
+		}
+		if e.Position.X <= before.X {
+			t.Fatalf("didn't move from %v", before)
+		}
+	}
+
+	prev, cur := e.Position.PrevVec2, e.Position.Vec2
+	mid := geom.Vec2{X: (prev.X + cur.X) / 2, Y: (prev.Y + cur.Y) / 2}
+	if got := e.Position.Interpolated(0.5); !near(got.X, mid.X) || !near(got.Y, mid.Y) {
+		t.Errorf("Interpolated(0.5) = %v, want the midpoint %v", got, mid)
+	}
+	if got := e.Position.Interpolated(0); got != prev {
+		t.Errorf("Interpolated(0) = %v, want PrevVec2 %v", got, prev)
+	}
+	if got := e.Position.Interpolated(1); got != cur {
+		t.Errorf("Interpolated(1) = %v, want the position %v", got, cur)
+	}
+}
+
+func TestSnapAndTeleport(t *testing.T) {
+	p := &PositionComponent{Vec2: geom.Vec2{X: 100, Y: 40}, PrevVec2: geom.Vec2{X: 0, Y: 40}}
+	if got := p.Interpolated(0.5); got != (geom.Vec2{X: 50, Y: 40}) {
+		t.Fatalf("interpolating by default gave %v, want (50, 40)", got)
+	}
+
+	p.Snap = true
+	for _, alpha := range []float64{0, 0.25, 1} {
+		if got := p.Interpolated(alpha); got != p.Vec2 {
+			t.Errorf("Snap Interpolated(%v) = %v, want the position %v", alpha, got, p.Vec2)
+		}
+	}
+
+	// A mid-frame Teleport isn't smeared from the old position
+	p.Snap = false
+	p.Teleport(geom.Vec2{X: 300, Y: 10})
+	for _, alpha := range []float64{0, 0.5, 1} {
+		if got := p.Interpolated(alpha); got != (geom.Vec2{X: 300, Y: 10}) {
+			t.Errorf("after Teleport Interpolated(%v) = %v, want (300, 10)", alpha, got)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Haptics rumbles every connected gamepad, e.g. on a hit alongside a camera
+// shake. A rumble fades out linearly over its duration. Call Update once per
+// frame. With no gamepad connected, or no vibration support, it does nothing.
+// The zero value is ready to use
+type Haptics struct {
+	strength  float64 // Strength the current rumble started at, 0-1
+	duration  float64 // Seconds the current rumble lasts
+	remaining float64 // Seconds left of the current rumble
+
+	gamepads []ebiten.GamepadID
+	vibrate  func(id ebiten.GamepadID, strength float64, d time.Duration) // nil = vibrateGamepad
+}
+
+// Rumble starts a rumble of strength 0-1 fading out over duration seconds. If
+// a rumble is already playing the stronger of the two (right now) wins
+func (h *Haptics) Rumble(strength, duration float64) {
+	if duration <= 0 || strength <= 0 {
+		return
+	}
+	strength = min(strength, 1)
+	if strength < h.Strength() {
+		return
+	}
+	h.strength = strength
+	h.duration = duration
+	h.remaining = duration
+}
+
+// Strength returns the current rumble strength, 0 when idle
+func (h *Haptics) Strength() float64 {
+	if h.remaining <= 0 {
+		return 0
+	}
+	return h.strength * h.remaining / h.duration
+}
+
+// Update fades the rumble by dt seconds and vibrates the gamepads at the new
+// strength until the next frame. Vibration is handed off to Ebiten so this
+// never blocks
+func (h *Haptics) Update(dt float64) {
+	if h.remaining <= 0 {
+		return
+	}
+	h.remaining = max(0, h.remaining-dt)
+	strength := h.Strength()
+	if strength <= 0 {
+		return
+	}
+
+	// Ask for a little more than a frame so there is no gap before the next
+	d := time.Duration(2 * dt * float64(time.Second))
+	vibrate := h.vibrate
+	if vibrate == nil {
+		vibrate = vibrateGamepad
+	}
+	h.gamepads = ebiten.AppendGamepadIDs(h.gamepads[:0])
+	for _, id := range h.gamepads {
+		vibrate(id, strength, d)
+	}
+}
+
+// vibrateGamepad vibrates a gamepad through Ebiten, both motors at strength
+func vibrateGamepad(id ebiten.GamepadID, strength float64, d time.Duration) {
+	ebiten.VibrateGamepad(id, &ebiten.VibrateGamepadOptions{
+		Duration:        d,
+		StrongMagnitude: strength,
+		WeakMagnitude:   strength,
+	})
+}
+
+// NewHaptics is constructor for Haptics
+func NewHaptics() *Haptics {
+	return &Haptics{}
+}
@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestHapticsDecay(t *testing.T) {
+	h := &Haptics{vibrate: func(ebiten.GamepadID, float64, time.Duration) {}}
+
+	h.Rumble(0.8, 1)
+	h.Update(0.25)
+	if got := h.Strength(); !near(got, 0.6) {
+		t.Errorf("Strength = %v after 0.25s of a 1s rumble at 0.8, want 0.6", got)
+	}
+
+	// A weaker rumble doesn't cut a stronger one short
+	h.Rumble(0.2, 5)
+	if got := h.Strength(); !near(got, 0.6) {
+		t.Errorf("Strength = %v after a weaker Rumble, want still 0.6", got)
+	}
+
+	h.Update(0.75)
+	if got := h.Strength(); got != 0 {
+		t.Errorf("Strength = %v after the rumble ended, want 0", got)
+	}
+}
+
+func TestHapticsZeroValue(t *testing.T) {
+	// Falls back to Ebiten's vibration rather than a nil func
+	var h Haptics
+	h.Rumble(1, 1)
+	h.Update(0.5)
+	if got := h.Strength(); !near(got, 0.5) {
+		t.Errorf("Strength = %v, want 0.5", got)
+	}
+}
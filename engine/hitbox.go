@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"github.com/samredway/ebx/collections"
+	"github.com/samredway/ebx/geom"
+)
+
+// Hitbox is a short lived attack volume placed directly in front of its owner's
+// collision box in the owner's Movement.FacingDir (e.g. a sword swing).
+// It is active for a window of frames counted from when it is spawned and hits
+// each target at most once
+type Hitbox struct {
+	Owner      *Entity
+	Size       geom.Size            // Hitbox dimensions
+	StartFrame int                  // First active frame (0 = the frame it is spawned)
+	EndFrame   int                  // Frame the hitbox is removed (exclusive)
+	Targets    func(e *Entity) bool // Which entities can be hit, nil = any other entity
+	OnHit      func(target *Entity) // Called once per target per swing
+	frame      int                  // Frames since spawn
+	hit        collections.Set[*Entity]
+}
+
+// Rect returns the hitbox in world coords. ok is false if the owner has no
+// collision box to place it against
+func (hb *Hitbox) Rect() (rect geom.Rect, ok bool) {
+	owner, ok := hb.Owner.CollisionRect()
+	if !ok {
+		return geom.Rect{}, false
+	}
+
+	var facing geom.Vec2I
+	if hb.Owner.Movement != nil {
+		facing = hb.Owner.Movement.FacingDir
+	}
+
+	// Centre on the owner then push out along each facing axis so the edges touch
+	w, h := float64(hb.Size.W), float64(hb.Size.H)
+	c := owner.Centre()
+	c.X += float64(facing.X) * (owner.W + w) / 2
+	c.Y += float64(facing.Y) * (owner.H + h) / 2
+
+	return geom.Rect{X: c.X - w/2, Y: c.Y - h/2, W: w, H: h}, true
+}
+
+// HitboxSystem checks active hitboxes against entity collision boxes
+type HitboxSystem struct {
+	entities *EntityManager
+	hitboxes []*Hitbox
+}
+
+// Spawn adds a hitbox to be checked from the next Update
+func (hs *HitboxSystem) Spawn(hb *Hitbox) {
+	hb.frame = 0
+	hb.hit = collections.NewSet[*Entity]()
+	hs.hitboxes = append(hs.hitboxes, hb)
+}
+
+// Update runs one frame: active hitboxes hit any new overlapping targets and
+// hitboxes whose window has ended are removed
+func (hs *HitboxSystem) Update(dt float64) {
+	live := hs.hitboxes[:0]
+	for _, hb := range hs.hitboxes {
+		if hb.frame >= hb.StartFrame && hb.frame < hb.EndFrame {
+			hs.check(hb)
+		}
+		hb.frame++
+		if hb.frame < hb.EndFrame && !hb.Owner.Dead && !hb.Owner.Despawned {
+			live = append(live, hb)
+		}
+	}
+	clear(hs.hitboxes[len(live):])
+	hs.hitboxes = live
+}
+
+func (hs *HitboxSystem) check(hb *Hitbox) {
+	box, ok := hb.Rect()
+	if !ok {
+		return
+	}
+	hs.entities.Each(func(e *Entity) {
+		if e == hb.Owner || e.Dead || hb.hit.Has(e) {
+			return
+		}
+		if hb.Targets != nil && !hb.Targets(e) {
+			return
+		}
+		target, ok := e.CollisionRect()
+		if !ok || !box.Overlaps(target) {
+			return
+		}
+		hb.hit.Add(e)
+		if hb.OnHit != nil {
+			hb.OnHit(e)
+		}
+	})
+}
+
+func NewHitboxSystem(ents *EntityManager) *HitboxSystem {
+	return &HitboxSystem{entities: ents}
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/samredway/ebx/geom"
+)
+
+func TestHitboxHitsOncePerActivation(t *testing.T) {
+	ents := NewEntityManager()
+	owner := mover(20, 20, 0, geom.Vec2I{})
+	owner.Movement.FacingDir = geom.Vec2I{X: 1}
+	// In front of the owner, under the hitbox at 28-36
+	target := mover(30, 20, 0, geom.Vec2I{})
+	ally := mover(30, 22, 0, geom.Vec2I{})
+	behind := mover(10, 20, 0, geom.Vec2I{})
+	for _, e := range []*Entity{owner, target, ally, behind} {
+		ents.Add(e)
+	}
+	hs := NewHitboxSystem(ents)
+
+	hits := map[*Entity]int{}
+	swing := func() *Hitbox {
+		return &Hitbox{
+			Owner:      owner,
+			Size:       geom.Size{W: 8, H: 8},
+			StartFrame: 1,
+			EndFrame:   5,
+			Targets:    func(e *Entity) bool { return e != ally },
+			OnHit:      func(e *Entity) { hits[e]++ },
+		}
+	}
+
+	hs.Spawn(swing())
+	hs.Update(1.0 / 60)
+	if hits[target] != 0 {
+		t.Errorf("hit %d times before StartFrame, want 0", hits[target])
+	}
+	for range 10 {
+		hs.Update(1.0 / 60)
+	}
+	if hits[target] != 1 {
+		t.Errorf("target hit %d times by one swing active for 4 frames, want once", hits[target])
+	}
+	if hits[ally] != 0 || hits[behind] != 0 || hits[owner] != 0 {
+		t.Errorf("hit ally %d, behind %d, owner %d times, want none", hits[ally], hits[behind], hits[owner])
+	}
+	if len(hs.hitboxes) != 0 {
+		t.Errorf("%d hitboxes left after EndFrame, want none", len(hs.hitboxes))
+	}
+
+	// A second swing is a new activation
+	hs.Spawn(swing())
+	for range 10 {
+		hs.Update(1.0 / 60)
+	}
+	if hits[target] != 2 {
+		t.Errorf("target hit %d times by two swings, want twice", hits[target])
+	}
+}
@@ -0,0 +1,36 @@
+package engine
+
+import "sync/atomic"
+
+// EntityId identifies an entity for the whole run, e.g. to refer to it in a
+// save file or over the network. 0 means no id
+type EntityId uint64
+
+// IdGen hands out EntityIds. Ids start at 1 and only ever go up, so an id is
+// never reused within a run (short of Reset). It is safe to call from multiple
+// goroutines. The zero value is ready to use
+type IdGen struct {
+	last atomic.Uint64
+}
+
+// Next returns a new id, greater than every id handed out or reserved before
+func (g *IdGen) Next() EntityId {
+	return EntityId(g.last.Add(1))
+}
+
+// Reserve makes sure Next never returns id or below, e.g. after loading saved
+// entities that already have ids
+func (g *IdGen) Reserve(id EntityId) {
+	for {
+		last := g.last.Load()
+		if uint64(id) <= last || g.last.CompareAndSwap(last, uint64(id)) {
+			return
+		}
+	}
+}
+
+// Reset starts handing out ids from 1 again. Ids handed out before will be
+// repeated, so only use it between independent runs, e.g. in tests
+func (g *IdGen) Reset() {
+	g.last.Store(0)
+}
@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestIdGenNext(t *testing.T) {
+	var g IdGen
+	prev := EntityId(0)
+	for range 100 {
+		id := g.Next()
+		if id <= prev {
+			t.Fatalf("Next = %d after %d, want increasing", id, prev)
+		}
+		prev = id
+	}
+
+	g.Reserve(500)
+	if id := g.Next(); id != 501 {
+		t.Errorf("Next after Reserve(500) = %d, want 501", id)
+	}
+	g.Reserve(10) // Below the last id, changes nothing
+	if id := g.Next(); id != 502 {
+		t.Errorf("Next after Reserve(10) = %d, want 502", id)
+	}
+
+	g.Reset()
+	if id := g.Next(); id != 1 {
+		t.Errorf("Next after Reset = %d, want 1", id)
+	}
+}
+
+func TestIdGenConcurrentUnique(t *testing.T) {
+	var g IdGen
+	const workers, each = 8, 1000
+	ids := make(chan EntityId, workers*each)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range each {
+				ids <- g.Next()
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[EntityId]bool{}
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("id %d handed out twice", id)
+		}
+		seen[id] = true
+	}
+}
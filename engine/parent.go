@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"math"
+
+	"github.com/samredway/ebx/geom"
+)
+
+// ParentComponent attaches an entity to another, e.g. a torch carried by the
+// player or a turret on a vehicle. The ParentSystem keeps the child at the
+// parent's position plus Offset and kills the child when the parent dies or
+// is despawned, the child isn't respawned with it
+type ParentComponent struct {
+	Entity *Entity   // The parent, must have a Position
+	Offset geom.Vec2 // Child position relative to the parent's position
+
+	// Local rotates Offset with the parent's Movement.FacingDir, taking +X as
+	// the facing direction, so e.g. a held item stays in front of its holder.
+	// When false Offset is in world space
+	Local bool
+}
+
+// ParentSystem moves child entities to their parents. Run it after the
+// MovementSystem so children use the parent's position for this frame
+type ParentSystem struct {
+	entities *EntityManager
+}
+
+func (ps *ParentSystem) Update(dt float64) {
+	ps.entities.Each(func(child *Entity) {
+		p := child.Parent
+		if p == nil || p.Entity == nil || child.Position == nil || child.Dead {
+			return
+		}
+		parent := p.Entity
+		if parent.Dead || parent.Despawned {
+			child.Dead = true
+			return
+		}
+		if parent.Position == nil {
+			return
+		}
+
+		offset := p.Offset
+		if p.Local && parent.Movement != nil {
+			offset = rotateToFacing(offset, parent.Movement.FacingDir)
+		}
+		child.Position.X = parent.Position.X + offset.X
+		child.Position.Y = parent.Position.Y + offset.Y
+	})
+}
+
+// rotateToFacing rotates v so that +X points along facing. A zero facing
+// leaves v unchanged
+func rotateToFacing(v geom.Vec2, facing geom.Vec2I) geom.Vec2 {
+	if facing.X == 0 && facing.Y == 0 {
+		return v
+	}
+	angle := math.Atan2(float64(facing.Y), float64(facing.X))
+	sin, cos := math.Sincos(angle)
+	return geom.Vec2{
+		X: v.X*cos - v.Y*sin,
+		Y: v.X*sin + v.Y*cos,
+	}
+}
+
+func NewParentSystem(ents *EntityManager) *ParentSystem {
+	return &ParentSystem{entities: ents}
+}
@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/samredway/ebx/geom"
+)
+
+func TestChildFollowsParent(t *testing.T) {
+	ents := NewEntityManager()
+	player := mover(20, 20, 60, geom.Vec2I{X: 1})
+	torch := &Entity{
+		Name:     "torch",
+		Position: &PositionComponent{},
+		Parent:   &ParentComponent{Entity: player, Offset: geom.Vec2{X: 4, Y: -6}},
+	}
+	ents.Add(player)
+	ents.Add(torch)
+	ms := NewMovementSystem(ents, roomMap(t), 0)
+	ps := NewParentSystem(ents)
+
+	for range 10 {
+		ms.Update(1.0 / 60)
+		ps.Update(1.0 / 60)
+	}
+	want := geom.Vec2{X: player.Position.X + 4, Y: player.Position.Y - 6}
+	if !near(torch.Position.X, want.X) || !near(torch.Position.Y, want.Y) {
+		t.Errorf("torch at %v, want parent position plus offset %v", torch.Position.Vec2, want)
+	}
+
+	// Local offsets turn with the parent, facing left puts it behind
+	torch.Parent.Local = true
+	player.Movement.DesiredDir = geom.Vec2I{X: -1}
+	ms.Update(1.0 / 60)
+	ps.Update(1.0 / 60)
+	want = geom.Vec2{X: player.Position.X - 4, Y: player.Position.Y + 6}
+	if !near(torch.Position.X, want.X) || !near(torch.Position.Y, want.Y) {
+		t.Errorf("local torch facing left at %v, want offset rotated to %v", torch.Position.Vec2, want)
+	}
+
+	player.Despawned = true
+	ps.Update(1.0 / 60)
+	if !torch.Dead {
+		t.Error("torch alive after its parent despawned, want it dead as for a dead parent")
+	}
+}
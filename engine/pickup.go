@@ -0,0 +1,37 @@
+package engine
+
+// PickupSystem moves items into inventories. When an entity with an
+// InventoryComponent overlaps an entity with an ItemComponent (using their
+// collision boxes) the item is added to the inventory and marked Dead. Only
+// the area around each collector is searched (see EntityManager.QueryRect)
+type PickupSystem struct {
+	entities *EntityManager
+	OnPickup func(collector, item *Entity) // Optional event, e.g. to update UI
+}
+
+func (ps *PickupSystem) Update(dt float64) {
+	ps.entities.Each(func(collector *Entity) {
+		if collector.Inventory == nil || collector.Dead {
+			return
+		}
+		box, ok := collector.CollisionRect()
+		if !ok {
+			return
+		}
+
+		for _, item := range ps.entities.QueryRect(box) {
+			if item.Item == nil || item == collector {
+				continue
+			}
+			collector.Inventory.Add(item.Item.ID, max(item.Item.Count, 1))
+			item.Dead = true
+			if ps.OnPickup != nil {
+				ps.OnPickup(collector, item)
+			}
+		}
+	})
+}
+
+func NewPickupSystem(ents *EntityManager) *PickupSystem {
+	return &PickupSystem{entities: ents}
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/samredway/ebx/geom"
+)
+
+func TestPickupWalkOntoItem(t *testing.T) {
+	ents := NewEntityManager()
+	player := mover(20, 24, 60, geom.Vec2I{X: 1})
+	player.Inventory = &InventoryComponent{}
+	item := func(x float64, id string, count int) *Entity {
+		return &Entity{
+			Position:  &PositionComponent{Vec2: geom.Vec2{X: x, Y: 24}},
+			Collision: &CollisionComponent{Size: geom.Size{W: 8, H: 8}},
+			Item:      &ItemComponent{ID: id, Count: count},
+		}
+	}
+	coin, gems := item(40, "coin", 0), item(60, "gem", 3)
+	ents.Add(player)
+	ents.Add(coin)
+	ents.Add(gems)
+	ms := NewMovementSystem(ents, roomMap(t), 0)
+	ps := NewPickupSystem(ents)
+	var picked []*Entity
+	ps.OnPickup = func(collector, item *Entity) {
+		if collector != player {
+			t.Errorf("picked up by %v, want the player", collector.Name)
+		}
+		picked = append(picked, item)
+	}
+
+	step := func() {
+		ms.Update(1.0 / 60)
+		ps.Update(1.0 / 60)
+		ents.RemoveDead()
+	}
+
+	// Short of the coin nothing is picked up
+	for range 5 {
+		step()
+	}
+	if len(picked) != 0 || player.Inventory.Count("coin") != 0 {
+		t.Fatalf("picked up %d items before reaching them", len(picked))
+	}
+
+	// Walk on past both
+	for range 60 {
+		step()
+	}
+	if got := player.Inventory.Count("coin"); got != 1 {
+		t.Errorf("%d coins, want 1 from a stack of 0", got)
+	}
+	if got := player.Inventory.Count("gem"); got != 3 {
+		t.Errorf("%d gems, want 3", got)
+	}
+	if !slices.Equal(picked, []*Entity{coin, gems}) {
+		t.Errorf("OnPickup called for %d items, want coin then gems once each", len(picked))
+	}
+	if len(ents.entities) != 1 {
+		t.Errorf("%d entities left, want only the player", len(ents.entities))
+	}
+}
@@ -0,0 +1,103 @@
+package engine
+
+import "time"
+
+// profilerSmoothing is how much each new frame moves a system's average, so
+// the reported times settle over roughly the last 10 frames
+const profilerSmoothing = 0.1
+
+// Profiler times system updates to show where frame time goes, e.g. in a
+// debug overlay. Wrap each system's Update once and call the wrapped func
+// instead. Timing only happens while Enabled. A system wrapped while the
+// Profiler is disabled is returned as is and never timed, so set Enabled
+// before wrapping, e.g. from a debug flag, to pay nothing in release builds
+//
+// Example:
+//
+//	s.prof = engine.NewProfiler()
+//	s.prof.Enabled = debug
+//	s.moveUpdate = s.prof.Wrap("movement", s.moveSys.Update)
+//
+//	// In Update
+//	s.moveUpdate(dt)
+//
+//	// In Draw
+//	for _, t := range s.prof.Report() {
+//		ebitenutil.DebugPrint(screen, fmt.Sprintf("%s %.2fms", t.Name, t.AvgMs))
+//	}
+type Profiler struct {
+	Enabled bool
+
+	timings []*SystemTiming // In the order they were first wrapped
+}
+
+// SystemTiming is the average update time of a system wrapped by a Profiler
+type SystemTiming struct {
+	Name  string
+	AvgMs float64 // Smoothed milliseconds per update, 0 until timed
+	timed bool
+}
+
+// Wrap returns update timed under name, or update itself if the Profiler
+// isn't Enabled
+func (p *Profiler) Wrap(name string, update func(dt float64)) func(dt float64) {
+	if !p.Enabled {
+		return update
+	}
+	t := p.timing(name)
+	return func(dt float64) {
+		if !p.Enabled {
+			update(dt)
+			return
+		}
+		start := time.Now()
+		update(dt)
+		t.add(time.Since(start))
+	}
+}
+
+// Report returns the timings of every wrapped system, in the order they were
+// wrapped
+func (p *Profiler) Report() []SystemTiming {
+	report := make([]SystemTiming, len(p.timings))
+	for i, t := range p.timings {
+		report[i] = *t
+	}
+	return report
+}
+
+// Reset clears every average, e.g. after toggling Enabled back on
+func (p *Profiler) Reset() {
+	for _, t := range p.timings {
+		t.AvgMs = 0
+		t.timed = false
+	}
+}
+
+// timing returns the timing for name, adding it if new
+func (p *Profiler) timing(name string) *SystemTiming {
+	for _, t := range p.timings {
+		if t.Name == name {
+			return t
+		}
+	}
+	t := &SystemTiming{Name: name}
+	p.timings = append(p.timings, t)
+	return t
+}
+
+// add folds one update's duration into the average
+func (t *SystemTiming) add(d time.Duration) {
+	ms := float64(d) / float64(time.Millisecond)
+	if !t.timed {
+		t.AvgMs = ms
+		t.timed = true
+		return
+	}
+	t.AvgMs += (ms - t.AvgMs) * profilerSmoothing
+}
+
+// NewProfiler is constructor for Profiler. It starts disabled
+func NewProfiler() *Profiler {
+	return &Profiler{}
+}
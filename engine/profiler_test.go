@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProfilerTimesEnabledSystems(t *testing.T) {
+	p := NewProfiler()
+	p.Enabled = true
+	calls := 0
+	slow := p.Wrap("slow", func(dt float64) {
+		calls++
+		time.Sleep(time.Millisecond)
+	})
+	fast := p.Wrap("fast", func(dt float64) {})
+
+	for range 3 {
+		slow(1.0 / 60)
+		fast(1.0 / 60)
+	}
+	if calls != 3 {
+		t.Errorf("wrapped update called %d times, want 3", calls)
+	}
+	report := p.Report()
+	if len(report) != 2 || report[0].Name != "slow" || report[1].Name != "fast" {
+		t.Fatalf("Report %+v, want slow then fast", report)
+	}
+	if report[0].AvgMs < 1 {
+		t.Errorf("slow AvgMs %v, want at least the 1ms it slept", report[0].AvgMs)
+	}
+
+	p.Reset()
+	if got := p.Report()[0].AvgMs; got != 0 {
+		t.Errorf("AvgMs %v after Reset, want 0", got)
+	}
+}
+
+func TestProfilerDisabledReturnsUpdate(t *testing.T) {
+	p := NewProfiler()
+	calls := 0
+	wrapped := p.Wrap("movement", func(dt float64) { calls++ })
+	wrapped(1.0 / 60)
+	if calls != 1 {
+		t.Errorf("update called %d times, want 1", calls)
+	}
+	if len(p.Report()) != 0 {
+		t.Errorf("Report %+v, want nothing timed while disabled", p.Report())
+	}
+}
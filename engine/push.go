@@ -0,0 +1,117 @@
+package engine
+
+import "math"
+
+// PushSystem separates overlapping dynamic entities, e.g. two slimes on the
+// same tile, so they push each other apart rather than stack. Entities take
+// part when they have a Movement component and collision that isn't Disabled.
+// Each overlapping pair is moved apart along the axis of least overlap and
+// shared by CollisionComponent.Mass, with Immovable entities never moved.
+// Pushes go through the MovementSystem's tile checks so nothing is pushed
+// into a wall. Run it after the MovementSystem, e.g.
+//
+//	s.Systems.Add(engine.PhaseMovement, moveSys)
+//	s.Systems.Add(engine.PhaseMovement, engine.NewPushSystem(ents, moveSys))
+type PushSystem struct {
+	// Strength is the fraction of an overlap removed per update, 0-1. Below 1
+	// entities drift apart over a few frames rather than snapping
+	Strength float64
+
+	entities *EntityManager
+	movement *MovementSystem
+	pushable []*Entity
+}
+
+func (ps *PushSystem) Update(dt float64) {
+	ps.pushable = ps.pushable[:0]
+	ps.entities.Each(func(e *Entity) {
+		if _, ok := e.CollisionRect(); ok && e.Movement != nil && !e.Dead {
+			ps.pushable = append(ps.pushable, e)
+		}
+	})
+
+	for i, a := range ps.pushable {
+		for _, b := range ps.pushable[i+1:] {
+			ps.separate(a, b)
+		}
+	}
+	clear(ps.pushable)
+}
+
+// separate pushes a and b out of each other along the axis of least overlap.
+// When a tile stops one of them short the other takes up the rest, unless it
+// is Immovable
+func (ps *PushSystem) separate(a, b *Entity) {
+	invA, invB := inverseMass(a.Collision), inverseMass(b.Collision)
+	if invA == 0 && invB == 0 {
+		return
+	}
+	boxA, _ := a.CollisionRect()
+	boxB, _ := b.CollisionRect()
+	if !boxA.Overlaps(boxB) {
+		return
+	}
+
+	overlapX := math.Min(boxA.X+boxA.W, boxB.X+boxB.W) - math.Max(boxA.X, boxB.X)
+	overlapY := math.Min(boxA.Y+boxA.H, boxB.Y+boxB.H) - math.Max(boxA.Y, boxB.Y)
+	alongX := overlapX <= overlapY
+	overlap, gap := overlapY, boxB.Centre().Y-boxA.Centre().Y
+	if alongX {
+		overlap, gap = overlapX, boxB.Centre().X-boxA.Centre().X
+	}
+	// a goes towards negative, b towards positive. Boxes sharing a centre are
+	// split the same way so the result doesn't depend on float noise
+	dir := 1.0
+	if gap < 0 {
+		dir = -1
+	}
+
+	total := overlap * ps.Strength
+	movedA := ps.push(a, -dir*total*invA/(invA+invB), alongX)
+	movedB := ps.push(b, dir*(total-movedA), alongX)
+	if short := total - movedA - movedB; short > 0 {
+		ps.push(a, -dir*short, alongX)
+	}
+}
+
+// push moves an entity by d along one axis, stopping at blocking tiles, and
+// returns the distance it actually moved. Immovable entities don't move
+func (ps *PushSystem) push(e *Entity, d float64, alongX bool) float64 {
+	if d == 0 || e.Collision.Immovable {
+		return 0
+	}
+	ms := ps.movement
+	size := e.CollisionSize()
+	w, h := float64(size.W), float64(size.H)
+	pos := e.Position
+	old := pos.Vec2
+
+	if alongX {
+		pos.X, pos.Y, _ = ms.resolveXAxis(pos.X, pos.Y, w, h, d, e.Collision)
+		return math.Abs(pos.X - old.X)
+	}
+	pos.X, pos.Y, _ = ms.resolveYAxis(pos.X, pos.Y, w, h, d, e.Collision)
+	return math.Abs(pos.Y - old.Y)
+}
+
+// inverseMass returns 1/Mass, with 0 Mass counting as 1 and Immovable as
+// infinite mass
+func inverseMass(c *CollisionComponent) float64 {
+	switch {
+	case c.Immovable:
+		return 0
+	case c.Mass <= 0:
+		return 1
+	}
+	return 1 / c.Mass
+}
+
+// NewPushSystem is constructor for PushSystem. It pushes entities with the
+// tile collision of ms
+func NewPushSystem(ents *EntityManager, ms *MovementSystem) *PushSystem {
+	return &PushSystem{
+		Strength: 1,
+		entities: ents,
+		movement: ms,
+	}
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/samredway/ebx/geom"
+)
+
+func TestPushSystemSeparates(t *testing.T) {
+	tests := []struct {
+		name         string
+		massA, massB float64
+		immA, immB   bool
+		wantA, wantB float64 // X after one update
+	}{
+		{"equal mass split evenly", 0, 0, false, false, 30, 38},
+		{"heavier moves less", 3, 1, false, false, 31, 39},
+		{"immovable stays put", 0, 0, true, false, 32, 40},
+		{"both immovable", 0, 0, true, true, 32, 36},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 8x8 boxes overlapping by 4px along X and fully along Y
+			ents := NewEntityManager()
+			a, b := mover(32, 24, 0, geom.Vec2I{}), mover(36, 24, 0, geom.Vec2I{})
+			a.Collision.Mass, a.Collision.Immovable = tt.massA, tt.immA
+			b.Collision.Mass, b.Collision.Immovable = tt.massB, tt.immB
+			ents.Add(a)
+			ents.Add(b)
+			ps := NewPushSystem(ents, NewMovementSystem(ents, roomMap(t), 0))
+
+			ps.Update(1.0 / 60)
+			if !near(a.Position.X, tt.wantA) || !near(b.Position.X, tt.wantB) {
+				t.Errorf("X = %v and %v, want %v and %v", a.Position.X, b.Position.X, tt.wantA, tt.wantB)
+			}
+			if a.Position.Y != 24 || b.Position.Y != 24 {
+				t.Errorf("Y = %v and %v, want unchanged along the axis of most overlap", a.Position.Y, b.Position.Y)
+			}
+			if !tt.immB && EntitiesOverlap(a, b) {
+				t.Error("still overlapping after a full strength push")
+			}
+		})
+	}
+}
+
+func TestPushSystemNotIntoWalls(t *testing.T) {
+	// a is against the left wall at 16, so b takes the push a can't
+	ents := NewEntityManager()
+	a, b := mover(17, 24, 0, geom.Vec2I{}), mover(21, 24, 0, geom.Vec2I{})
+	ents.Add(a)
+	ents.Add(b)
+	ps := NewPushSystem(ents, NewMovementSystem(ents, roomMap(t), 0))
+
+	ps.Update(1.0 / 60)
+	if a.Position.X < 16 {
+		t.Errorf("a pushed into the wall to X %v", a.Position.X)
+	}
+	if EntitiesOverlap(a, b) {
+		t.Errorf("still overlapping at X %v and %v", a.Position.X, b.Position.X)
+	}
+}
@@ -0,0 +1,67 @@
+package engine
+
+import "fmt"
+
+// Phase is a stage of a frame's update. Systems in earlier phases run first,
+// so e.g. the MovementSystem always sees this frame's input and animation
+// always sees this frame's movement. Drawing happens after every phase, in
+// the Scene's Draw
+type Phase int
+
+const (
+	PhaseInput     Phase = iota // Reading input into intents, e.g. DesiredDir
+	PhaseScripts                // AI and entity Scripts (EntityManager.Update)
+	PhaseMovement               // MovementSystem, then anything following it such as the ParentSystem
+	PhaseCollision              // Entity overlaps: sensors, pickups, hitboxes
+	PhaseAnimation              // Picking frames from the final state of the frame
+	PhaseRender                 // Readying the frame's Draw, e.g. RenderSystem moving the camera
+	PhaseCleanup                // Removing what the frame killed, e.g. EntityManager.RemoveDead
+
+	phaseCount
+)
+
+// System is anything updated once per frame, like the engine's systems
+type System interface {
+	Update(dt float64)
+}
+
+// SystemFunc lets a plain func be used as a System, e.g.
+//
+//	runner.Add(engine.PhaseCleanup, engine.SystemFunc(func(float64) { ents.RemoveDead() }))
+type SystemFunc func(dt float64)
+
+func (f SystemFunc) Update(dt float64) { f(dt) }
+
+// SystemRunner updates systems in Phase order, and within a phase in the
+// order they were added. The zero value is ready to use
+type SystemRunner struct {
+	phases [phaseCount][]System
+}
+
+// Add registers s to run in phase
+func (r *SystemRunner) Add(phase Phase, s System) {
+	if phase < 0 || phase >= phaseCount {
+		panic(fmt.Sprintf("invalid system phase %d", phase))
+	}
+	r.phases[phase] = append(r.phases[phase], s)
+}
+
+// Reset removes every system, e.g. when a scene exits so re-entering it
+// doesn't register its systems twice
+func (r *SystemRunner) Reset() {
+	r.phases = [phaseCount][]System{}
+}
+
+// Update runs every system in phase order
+func (r *SystemRunner) Update(dt float64) {
+	for _, systems := range r.phases {
+		for _, s := range systems {
+			s.Update(dt)
+		}
+	}
+}
+
+// NewSystemRunner is constructor for SystemRunner
+func NewSystemRunner() *SystemRunner {
+	return &SystemRunner{}
+}
@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSystemRunnerPhaseOrder(t *testing.T) {
+	var got []string
+	system := func(name string) System {
+		return SystemFunc(func(float64) { got = append(got, name) })
+	}
+
+	// Added out of phase order, in-phase order is the order added
+	var r SystemRunner
+	r.Add(PhaseCleanup, system("cleanup"))
+	r.Add(PhaseAnimation, system("animation"))
+	r.Add(PhaseMovement, system("movement"))
+	r.Add(PhaseMovement, system("parent"))
+	r.Add(PhaseRender, system("render"))
+	r.Add(PhaseInput, system("input"))
+	r.Add(PhaseCollision, system("collision"))
+	r.Add(PhaseScripts, system("scripts"))
+
+	r.Update(1.0 / 60)
+	want := []string{"input", "scripts", "movement", "parent", "collision", "animation", "render", "cleanup"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ran %v, want %v", got, want)
+	}
+}
+
+func TestSystemRunnerInvalidPhase(t *testing.T) {
+	for _, phase := range []Phase{-1, phaseCount} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Add in phase %d didn't panic", phase)
+				}
+			}()
+			var r SystemRunner
+			r.Add(phase, SystemFunc(func(float64) {}))
+		}()
+	}
+}
@@ -0,0 +1,97 @@
+package engine
+
+import "fmt"
+
+// Scheduler runs callbacks after a delay or on a fixed interval, e.g. spawning
+// a wave of enemies every 5 seconds, without scattering float timers across
+// scripts. Callbacks are run from Update so they are on the game goroutine
+//
+// Example:
+//
+//	wave := s.sched.Every(5, spawnWave)
+//	s.sched.After(0.5, func() { s.banner = nil })
+//	...
+//	wave.Cancel()
+type Scheduler struct {
+	actions []*ScheduledAction
+}
+
+// ScheduledAction is a handle to a callback registered with a Scheduler
+type ScheduledAction struct {
+	fn        func()
+	remaining float64 // seconds until next call
+	interval  float64 // 0 = one off
+	done      bool
+}
+
+// Cancel stops the action from running again. Safe to call more than once and
+// from inside a scheduler callback
+func (a *ScheduledAction) Cancel() { a.done = true }
+
+// Done reports whether a one off action has run or the action was cancelled
+func (a *ScheduledAction) Done() bool { return a.done }
+
+// After calls fn once, delay seconds from now
+func (s *Scheduler) After(delay float64, fn func()) *ScheduledAction {
+	a := &ScheduledAction{fn: fn, remaining: delay}
+	s.actions = append(s.actions, a)
+	return a
+}
+
+// Every calls fn every interval seconds, the first call being one interval
+// from now. interval must be > 0
+func (s *Scheduler) Every(interval float64, fn func()) *ScheduledAction {
+	if interval <= 0 {
+		panic(fmt.Sprintf("Scheduler.Every: interval must be > 0, got %v", interval))
+	}
+	a := &ScheduledAction{fn: fn, remaining: interval, interval: interval}
+	s.actions = append(s.actions, a)
+	return a
+}
+
+// Update advances every action by dt seconds. If dt spans several intervals a
+// repeating action is called once per interval elapsed so none are dropped.
+// Actions scheduled from inside a callback start ticking on the next Update
+func (s *Scheduler) Update(dt float64) {
+	n := len(s.actions)
+	for _, a := range s.actions[:n] {
+		if a.done {
+			continue
+		}
+		a.remaining -= dt
+		for a.remaining <= 0 && !a.done {
+			a.fn()
+			if a.interval == 0 {
+				a.done = true
+				break
+			}
+			a.remaining += a.interval
+		}
+	}
+
+	// drop finished actions, keeping any added by callbacks during this update
+	kept := s.actions[:0]
+	for _, a := range s.actions {
+		if !a.done {
+			kept = append(kept, a)
+		}
+	}
+	clear(s.actions[len(kept):])
+	s.actions = kept
+}
+
+// Len returns the number of pending actions
+func (s *Scheduler) Len() int { return len(s.actions) }
+
+// Clear cancels every pending action
+func (s *Scheduler) Clear() {
+	for _, a := range s.actions {
+		a.done = true
+	}
+	s.actions = nil
+}
+
+// NewScheduler is constructor for Scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
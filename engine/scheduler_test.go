@@ -0,0 +1,77 @@
+package engine
+
+import "testing"
+
+func TestSchedulerAfterFiresOnce(t *testing.T) {
+	s := NewScheduler()
+	calls := 0
+	a := s.After(0.5, func() { calls++ })
+
+	s.Update(0.25)
+	if calls != 0 || a.Done() {
+		t.Fatalf("fired %d times before the delay, done %v", calls, a.Done())
+	}
+	s.Update(0.25)
+	if calls != 1 || !a.Done() {
+		t.Fatalf("fired %d times at the delay, done %v, want once and done", calls, a.Done())
+	}
+	for range 10 {
+		s.Update(1)
+	}
+	if calls != 1 || s.Len() != 0 {
+		t.Errorf("fired %d times with %d pending, want once and none", calls, s.Len())
+	}
+}
+
+func TestSchedulerEveryRepeats(t *testing.T) {
+	s := NewScheduler()
+	calls := 0
+	s.Every(1, func() { calls++ })
+
+	for i := 1; i <= 4; i++ {
+		s.Update(0.5)
+		s.Update(0.5)
+		if calls != i {
+			t.Errorf("after %ds fired %d times, want %d", i, calls, i)
+		}
+	}
+}
+
+func TestSchedulerLargeDtCatchesUp(t *testing.T) {
+	s := NewScheduler()
+	calls := 0
+	s.Every(1, func() { calls++ })
+
+	// 3.5 intervals in one update fires for each of the 3 elapsed
+	s.Update(3.5)
+	if calls != 3 {
+		t.Errorf("fired %d times over 3.5s, want 3", calls)
+	}
+	// The half interval left over carries into the next update
+	s.Update(0.5)
+	if calls != 4 {
+		t.Errorf("fired %d times over 4s, want 4", calls)
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	s := NewScheduler()
+	calls := 0
+	a := s.After(1, func() { calls++ })
+
+	s.Update(0.5)
+	a.Cancel()
+	s.Update(2)
+	if calls != 0 || !a.Done() || s.Len() != 0 {
+		t.Errorf("cancelled After fired %d times, done %v, %d pending", calls, a.Done(), s.Len())
+	}
+
+	// Cancelling from inside a callback stops any catch up calls
+	var e *ScheduledAction
+	calls = 0
+	e = s.Every(1, func() { calls++; e.Cancel() })
+	s.Update(5)
+	if calls != 1 || s.Len() != 0 {
+		t.Errorf("Every cancelled by its own callback fired %d times with %d pending, want once and none", calls, s.Len())
+	}
+}
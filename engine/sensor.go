@@ -0,0 +1,47 @@
+package engine
+
+import "slices"
+
+// SensorSystem refreshes SensorComponent.Overlapping with the live entities
+// whose collision boxes overlap each sensor. Sensors don't affect movement,
+// the MovementSystem only ever looks at CollisionComponent
+type SensorSystem struct {
+	entities *EntityManager
+	OnEnter  func(sensor, other *Entity) // Optional event when an overlap starts
+	OnExit   func(sensor, other *Entity) // Optional event when an overlap ends
+}
+
+func (ss *SensorSystem) Update(dt float64) {
+	ss.entities.Each(func(e *Entity) {
+		if e.Sensor == nil {
+			return
+		}
+		prev := e.Sensor.Overlapping
+		var now []*Entity
+
+		if sensorBox, ok := e.SensorRect(); ok && !e.Dead {
+			now = ss.entities.QueryRect(sensorBox)
+			now = slices.DeleteFunc(now, func(other *Entity) bool { return other == e })
+		}
+		e.Sensor.Overlapping = now
+
+		if ss.OnEnter != nil {
+			for _, other := range now {
+				if !slices.Contains(prev, other) {
+					ss.OnEnter(e, other)
+				}
+			}
+		}
+		if ss.OnExit != nil {
+			for _, other := range prev {
+				if !slices.Contains(now, other) {
+					ss.OnExit(e, other)
+				}
+			}
+		}
+	})
+}
+
+func NewSensorSystem(ents *EntityManager) *SensorSystem {
+	return &SensorSystem{entities: ents}
+}
@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/samredway/ebx/geom"
+)
+
+func TestSensorReportsOverlapWithoutBlocking(t *testing.T) {
+	ents := NewEntityManager()
+	walker := mover(20, 20, 60, geom.Vec2I{X: 1})
+	// Standing still with a detection range covering 34-66 across
+	guard := mover(50, 20, 0, geom.Vec2I{})
+	guard.Name = "guard"
+	guard.Sensor = &SensorComponent{Size: geom.Size{W: 32, H: 24}, Offset: geom.Vec2{X: -16, Y: -8}}
+	ents.Add(walker)
+	ents.Add(guard)
+	ms := NewMovementSystem(ents, roomMap(t), 0)
+	ss := NewSensorSystem(ents)
+	var entered []*Entity
+	ss.OnEnter = func(sensor, other *Entity) {
+		if sensor == guard {
+			entered = append(entered, other)
+		}
+	}
+
+	for range 20 {
+		ms.Update(1.0 / 60)
+		ss.Update(1.0 / 60)
+	}
+	if !near(walker.Position.X, 40) {
+		t.Errorf("walker at X %v, want 40 as if the sensor wasn't there", walker.Position.X)
+	}
+	if !slices.Contains(guard.Sensor.Overlapping, walker) {
+		t.Errorf("guard Overlapping %v, want the walker in it", guard.Sensor.Overlapping)
+	}
+	if slices.Contains(guard.Sensor.Overlapping, guard) {
+		t.Error("guard's sensor reports the guard itself")
+	}
+	if len(entered) != 1 || entered[0] != walker {
+		t.Errorf("OnEnter reported %v, want the walker once", entered)
+	}
+}
@@ -0,0 +1,105 @@
+package engine
+
+// Sequence runs a list of steps one after another from a Scene's Update, e.g.
+// an intro that shows some text, waits, pans the camera and then moves on to
+// the next scene. Each step runs an action, waits some time and/or until a
+// condition is met
+//
+// Example:
+//
+//	s.intro = engine.NewSequence().
+//		Do(func() { s.showText("Long ago...") }).
+//		Wait(2).
+//		Do(func() { s.render.SetCameraTarget(s.castle, 1.5) }).
+//		Until(func() bool { return !s.cam.Transitioning() })
+//
+//	// In Update
+//	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+//		s.intro.SkipAll()
+//	}
+//	if s.intro.Update(dt) {
+//		return NewLevelScene(), nil
+//	}
+type Sequence struct {
+	steps   []sequenceStep
+	current int
+	started bool    // current step's action has run
+	elapsed float64 // seconds spent waiting in the current step
+	skip    bool    // end the current step early
+	skipAll bool    // end every step early
+}
+
+type sequenceStep struct {
+	do    func()
+	wait  float64
+	until func() bool
+}
+
+// Do adds a step that runs fn and moves straight on
+func (s *Sequence) Do(fn func()) *Sequence {
+	s.steps = append(s.steps, sequenceStep{do: fn})
+	return s
+}
+
+// Wait adds a step that waits seconds
+func (s *Sequence) Wait(seconds float64) *Sequence {
+	s.steps = append(s.steps, sequenceStep{wait: seconds})
+	return s
+}
+
+// Until adds a step that waits until cond returns true. cond is checked once
+// per Update
+func (s *Sequence) Until(cond func() bool) *Sequence {
+	s.steps = append(s.steps, sequenceStep{until: cond})
+	return s
+}
+
+// Skip ends the current step's wait early, e.g. to skip a line of dialogue
+func (s *Sequence) Skip() { s.skip = true }
+
+// SkipAll ends the sequence on the next Update. The actions of the remaining
+// Do steps still run, in order, so the game ends up in the same state as if
+// the sequence had played out
+func (s *Sequence) SkipAll() { s.skipAll = true }
+
+// Done reports whether every step has finished
+func (s *Sequence) Done() bool { return s.current >= len(s.steps) }
+
+// Update advances the sequence by dt seconds, running as many steps as finish
+// within it, and reports whether the sequence is done
+func (s *Sequence) Update(dt float64) bool {
+	budget := dt
+	for !s.Done() {
+		step := s.steps[s.current]
+		if !s.started {
+			s.started = true
+			s.elapsed = 0
+			if step.do != nil {
+				step.do()
+			}
+		}
+
+		skipping := s.skip || s.skipAll
+		if step.wait > 0 && !skipping {
+			s.elapsed += budget
+			if s.elapsed < step.wait {
+				return false
+			}
+			// Carry time left over into the next step
+			budget = s.elapsed - step.wait
+		}
+		if step.until != nil && !skipping && !step.until() {
+			return false
+		}
+
+		s.current++
+		s.started = false
+		s.skip = false
+	}
+	return true
+}
+
+// NewSequence is constructor for Sequence
+func NewSequence() *Sequence {
+	return &Sequence{}
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSequenceTwoSteps(t *testing.T) {
+	var log []string
+	ready := false
+	s := NewSequence().
+		Do(func() { log = append(log, "text") }).
+		Wait(1).
+		Do(func() { log = append(log, "pan") }).
+		Until(func() bool { return ready })
+
+	if s.Update(0.5) {
+		t.Fatal("done half way through the wait")
+	}
+	if !slices.Equal(log, []string{"text"}) {
+		t.Fatalf("ran %v during the wait, want only the first step", log)
+	}
+	// The 0.25s left after the wait carries into the second step
+	if s.Update(0.75) {
+		t.Fatal("done before the condition was met")
+	}
+	if !slices.Equal(log, []string{"text", "pan"}) {
+		t.Fatalf("ran %v after the wait, want both steps", log)
+	}
+	if s.Update(1.0 / 60) {
+		t.Fatal("done before the condition was met")
+	}
+	ready = true
+	if !s.Update(1.0/60) || !s.Done() {
+		t.Error("not done once the condition was met")
+	}
+}
+
+func TestSequenceSkipAllRunsActions(t *testing.T) {
+	var log []string
+	s := NewSequence().
+		Do(func() { log = append(log, "text") }).
+		Wait(10).
+		Do(func() { log = append(log, "pan") }).
+		Until(func() bool { return false })
+
+	s.Update(1.0 / 60)
+	s.SkipAll()
+	if !s.Update(1.0 / 60) {
+		t.Fatal("not done after SkipAll")
+	}
+	if !slices.Equal(log, []string{"text", "pan"}) {
+		t.Errorf("ran %v after SkipAll, want every action once", log)
+	}
+}
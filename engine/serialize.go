@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Serializable is implemented by components (and scripts) that can save and
+// restore their own state, so saving an entity doesn't need to know about
+// every component type. State is JSON, so fields added later or removed are
+// ignored on load rather than failing
+type Serializable interface {
+	MarshalState() ([]byte, error)
+	UnmarshalState(data []byte) error
+}
+
+// EntityState is the saved state of an entity's Serializable parts by name
+type EntityState map[string]json.RawMessage
+
+// SaveEntity gathers the state of every Serializable part of e: the built in
+// components that support it and the Script if it implements Serializable
+func SaveEntity(e *Entity) (EntityState, error) {
+	state := EntityState{}
+	for name, part := range serializableParts(e) {
+		data, err := part.MarshalState()
+		if err != nil {
+			return nil, fmt.Errorf("failed to save %s of %s: %w", name, e.Name, err)
+		}
+		state[name] = data
+	}
+	return state, nil
+}
+
+// LoadEntity restores state saved by SaveEntity into e. Parts missing from
+// state are left as they are and saved parts e no longer has are ignored
+func LoadEntity(e *Entity, state EntityState) error {
+	for name, part := range serializableParts(e) {
+		data, ok := state[name]
+		if !ok {
+			continue
+		}
+		if err := part.UnmarshalState(data); err != nil {
+			return fmt.Errorf("failed to load %s of %s: %w", name, e.Name, err)
+		}
+	}
+	return nil
+}
+
+// serializableParts returns the entity's Serializable parts by name
+func serializableParts(e *Entity) map[string]Serializable {
+	parts := map[string]Serializable{}
+	if e.Position != nil {
+		parts["position"] = e.Position
+	}
+	if e.Item != nil {
+		parts["item"] = e.Item
+	}
+	if e.Inventory != nil {
+		parts["inventory"] = e.Inventory
+	}
+	if s, ok := e.Script.(Serializable); ok {
+		parts["script"] = s
+	}
+	return parts
+}
+
+func (p *PositionComponent) MarshalState() ([]byte, error) {
+	return json.Marshal(p.Vec2)
+}
+
+func (p *PositionComponent) UnmarshalState(data []byte) error {
+	if err := json.Unmarshal(data, &p.Vec2); err != nil {
+		return err
+	}
+	p.PrevVec2 = p.Vec2
+	return nil
+}
+
+func (i *ItemComponent) MarshalState() ([]byte, error) {
+	return json.Marshal(i)
+}
+
+func (i *ItemComponent) UnmarshalState(data []byte) error {
+	return json.Unmarshal(data, i)
+}
+
+func (inv *InventoryComponent) MarshalState() ([]byte, error) {
+	return json.Marshal(inv.Items)
+}
+
+func (inv *InventoryComponent) UnmarshalState(data []byte) error {
+	items := map[string]int{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	inv.Items = items
+	return nil
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"encoding/json"
+	"maps"
+	"testing"
+
+	"github.com/samredway/ebx/geom"
+)
+
+// chestScript is a Script with its own saved state
+type chestScript struct {
+	Opened bool
+}
+
+func (cs *chestScript) Update(*Entity, float64) {}
+
+func (cs *chestScript) MarshalState() ([]byte, error) { return json.Marshal(cs) }
+
+func (cs *chestScript) UnmarshalState(data []byte) error { return json.Unmarshal(data, cs) }
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	saved := &Entity{
+		Name:      "chest",
+		Position:  &PositionComponent{Vec2: geom.Vec2{X: 12.5, Y: 40}},
+		Item:      &ItemComponent{ID: "key", Count: 2},
+		Inventory: &InventoryComponent{Items: map[string]int{"coin": 7, "gem": 1}},
+		Script:    &chestScript{Opened: true},
+	}
+	state, err := SaveEntity(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Through JSON as a save file would
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var read EntityState
+	if err := json.Unmarshal(data, &read); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &Entity{
+		Name:      "chest",
+		Position:  &PositionComponent{Vec2: geom.Vec2{X: 1, Y: 1}, PrevVec2: geom.Vec2{X: 1, Y: 1}},
+		Item:      &ItemComponent{},
+		Inventory: &InventoryComponent{Items: map[string]int{"junk": 3}},
+		Script:    &chestScript{},
+	}
+	if err := LoadEntity(loaded, read); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Position.Vec2 != saved.Position.Vec2 {
+		t.Errorf("Position %v, want %v", loaded.Position.Vec2, saved.Position.Vec2)
+	}
+	if loaded.Position.PrevVec2 != saved.Position.Vec2 {
+		t.Errorf("PrevVec2 %v, want the loaded position so it doesn't interpolate from the old one", loaded.Position.PrevVec2)
+	}
+	if *loaded.Item != *saved.Item {
+		t.Errorf("Item %+v, want %+v", *loaded.Item, *saved.Item)
+	}
+	if !maps.Equal(loaded.Inventory.Items, saved.Inventory.Items) {
+		t.Errorf("Inventory %v, want replaced by %v", loaded.Inventory.Items, saved.Inventory.Items)
+	}
+	if !loaded.Script.(*chestScript).Opened {
+		t.Error("Script state not restored")
+	}
+}
+
+func TestLoadEntityBadState(t *testing.T) {
+	e := &Entity{Name: "chest", Item: &ItemComponent{}}
+	err := LoadEntity(e, EntityState{"item": json.RawMessage(`"not an item"`)})
+	if err == nil {
+		t.Error("loaded malformed item state without error")
+	}
+}
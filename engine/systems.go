@@ -3,109 +3,434 @@ package engine
 import (
 	"fmt"
 	"image"
+	"image/color"
 	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 	"github.com/samredway/ebx/assetmgr"
 	"github.com/samredway/ebx/camera"
+	"github.com/samredway/ebx/collision"
 	"github.com/samredway/ebx/geom"
 )
 
-// collisionEpsilon is a tiny offset to prevent floating-point precision issues
-// when resolving collisions, avoiding player jitter against walls.
-const collisionEpsilon = 0.001
+// shadowImgSize is the size px of the circle image shadows are scaled from
+const shadowImgSize = 32
 
-// RenderSystem gets run in the Scene.Draw() method
+// placeholderSize is the size px of the placeholder drawn for an entity with
+// no image and no collision size to go by
+const placeholderSize = 16
+
+// placeholderColour is the debug magenta drawn for entities with no image
+var placeholderColour = color.RGBA{R: 0xff, B: 0xff, A: 0xff}
+
+// RenderSystem gets run in the Scene.Draw() method. Its Update advances the
+// camera, so add it to the scene's Systems (in PhaseRender) as well
 type RenderSystem struct {
 	entities  *EntityManager
 	camera    *camera.Camera
 	tileMap   *assetmgr.TileMap
 	camTarget *Entity // Entity for camera to center on (usaully Player)
+	shadowImg *ebiten.Image
+	origin    geom.Vec2 // Screen position of the area being drawn into
+	flashes   []*screenFlash
+
+	// FlashAfterUI stops Draw drawing FlashScreen flashes so they can be drawn
+	// over the scene's UI with DrawFlashes
+	FlashAfterUI bool
+
+	// DrawOrder lists the passes Draw makes, in order. nil uses
+	// DefaultDrawOrder for the tile map
+	DrawOrder []DrawPass
+
+	// Filter is used to scale tiles and sprites. The zero value is
+	// ebiten.FilterNearest, keeping pixel art crisp when zoomed. Use
+	// ebiten.FilterLinear for smoother scaling of high resolution art
+	Filter ebiten.Filter
+
+	// HidePlaceholders skips entities with no Render.Img rather than drawing
+	// a magenta placeholder box, e.g. for release builds
+	HidePlaceholders bool
+
+	// opts is reused for every draw to avoid an allocation per sprite. Draws
+	// are issued in entity order and Ebiten merges consecutive draws from the
+	// same source atlas into one draw call, so many identical sprites are
+	// already batched without reordering them
+	opts ebiten.DrawImageOptions
+}
+
+// DrawPassKind is a kind of RenderSystem draw pass
+type DrawPassKind int
+
+const (
+	PassImageLayers DrawPassKind = iota // The map's image layers authored before tile layer Layer
+	PassTileLayer                       // Tile layer Layer
+	PassEntities                        // Every entity with a RenderComponent
+	PassFlashes                         // FlashScreen flashes (unless FlashAfterUI is set)
+)
+
+// DrawPass is one step of a RenderSystem's DrawOrder. Layer is only used by
+// the layer passes
+type DrawPass struct {
+	Kind  DrawPassKind
+	Layer int
+}
+
+// DefaultDrawOrder returns the draw order used when RenderSystem.DrawOrder is
+// nil: every tile layer in map order with image layers where they were
+// authored, then entities, then flashes. To draw a layer over the entities,
+// e.g. tree tops, move its PassTileLayer after PassEntities
+func DefaultDrawOrder(tm *assetmgr.TileMap) []DrawPass {
+	var order []DrawPass
+	for layer := range tm.NumLayers() {
+		order = append(order,
+			DrawPass{Kind: PassImageLayers, Layer: layer},
+			DrawPass{Kind: PassTileLayer, Layer: layer},
+		)
+	}
+	return append(order,
+		DrawPass{Kind: PassImageLayers, Layer: tm.NumLayers()},
+		DrawPass{Kind: PassEntities},
+		DrawPass{Kind: PassFlashes},
+	)
+}
+
+// Update moves the camera to its target for the frame, easing any look-ahead
+// and SetCameraTarget pan by dt, and fades screen flashes. Draw only reads
+// this state, so drawing more than once a frame (or not at all) doesn't
+// change it
+func (rs *RenderSystem) Update(dt float64) {
+	if rs.camTarget == nil || rs.camTarget.Position == nil {
+		panic("Camera target has not been set")
+	}
+	rs.followTarget(dt)
+	rs.updateFlashes(dt)
 }
 
 // Draw draws entities and tiles to screen
 func (rs *RenderSystem) Draw(screen *ebiten.Image) {
-	if rs.camTarget.Position == nil && rs.camTarget == nil {
-		panic("Camera target has not been set")
+	rs.origin = geom.Vec2{}
+	rs.draw(screen)
+}
+
+// DrawIn draws entities and tiles into the area of screen, resizing the
+// camera's viewport to fit. For split screen give each player a RenderSystem
+// with its own camera and target sharing the same entities and tile map,
+// updated each frame, then call DrawIn for each with a different area
+func (rs *RenderSystem) DrawIn(screen *ebiten.Image, area image.Rectangle) {
+	size := geom.Size{W: area.Dx(), H: area.Dy()}
+	if rs.camera.Viewport() != size {
+		rs.camera.SetViewport(size)
 	}
-	rs.camera.CentreOn(rs.camTarget.Position.Vec2)
+	rs.origin = geom.Vec2{X: float64(area.Min.X), Y: float64(area.Min.Y)}
+	// Drawing to a sub image clips to it but keeps the screen's coordinates
+	rs.draw(screen.SubImage(area).(*ebiten.Image))
+}
 
-	// Draw tiles first
-	rs.drawTiles(screen)
+func (rs *RenderSystem) draw(screen *ebiten.Image) {
+	order := rs.DrawOrder
+	if order == nil {
+		order = DefaultDrawOrder(rs.tileMap)
+	}
+	for _, pass := range order {
+		switch pass.Kind {
+		case PassImageLayers:
+			rs.drawImageLayers(pass.Layer, screen)
+		case PassTileLayer:
+			rs.drawTileLayer(pass.Layer, screen)
+		case PassEntities:
+			rs.drawEntities(screen)
+		case PassFlashes:
+			if !rs.FlashAfterUI {
+				rs.DrawFlashes(screen)
+			}
+		}
+	}
+}
 
-	// Draw entities
+func (rs *RenderSystem) drawEntities(screen *ebiten.Image) {
 	rs.entities.Each(func(e *Entity) {
 		if e.Position == nil || e.Render == nil {
 			return
 		}
 		if e.Render.Img == nil {
-			panic(fmt.Errorf("Entity %s does not have image", e.Name))
+			// No art yet, make that obvious rather than crash
+			if !rs.HidePlaceholders {
+				rs.drawPlaceholder(e, screen)
+			}
+			return
+		}
+		img, ok := e.Render.crop(e.Render.Img)
+		if !ok {
+			return
+		}
+		if e.Render.Shadow != nil {
+			rs.drawShadow(e.Position.Vec2, img, e.Render.Shadow, screen)
+		}
+		r := e.Render
+		offset := r.Offset
+		if r.FlipX {
+			offset.X = flippedOffsetX(e, img.Bounds().Dx())
 		}
-		rs.drawToScreen(e.Position.Vec2, e.Render.Img, screen)
+		pos := geom.Vec2{X: e.Position.X + offset.X, Y: e.Position.Y + offset.Y}
+		if r.FadeFrom != nil {
+			if from, ok := r.crop(r.FadeFrom); ok {
+				rs.drawToScreen(pos, from, screen, 1, 1-r.FadeAlpha, r.FlipX)
+			}
+			rs.drawToScreen(pos, img, screen, 1, r.FadeAlpha, r.FlipX)
+			return
+		}
+		rs.drawToScreen(pos, img, screen, 1, 1, r.FlipX)
 	})
 }
 
-func (rs *RenderSystem) drawTiles(screen *ebiten.Image) {
+// flippedOffsetX returns Render.Offset.X mirrored for a FlipX sprite imgW px
+// wide, about the centre of the entity's collision box so the sprite stays
+// over the box when it turns. Without a collision box it is mirrored about
+// the centre of the sprite drawn at no offset
+func flippedOffsetX(e *Entity, imgW int) float64 {
+	boxX, boxW := 0.0, float64(imgW)
+	if c := e.Collision; c != nil {
+		if size := e.CollisionSize(); size.W > 0 {
+			boxX, boxW = c.Offset.X, float64(size.W)
+		}
+	}
+	// Keep the sprite's left edge as far from the box's right edge as it was
+	// from the left
+	return 2*boxX + boxW - float64(imgW) - e.Render.Offset.X
+}
+
+// SetCameraTarget changes which entity the camera follows, panning smoothly
+// over pan seconds (0 = cut straight to it)
+func (rs *RenderSystem) SetCameraTarget(e *Entity, pan float64) {
+	rs.camTarget = e
+	rs.camera.TransitionTo(pan)
+}
+
+// followTarget moves the camera to the target, leading it in the direction
+// it is moving when the camera has LookAhead set
+func (rs *RenderSystem) followTarget(dt float64) {
+	var dir geom.Vec2
+	if m := rs.camTarget.Movement; m != nil {
+		dir = m.Velocity
+	}
+	rs.camera.Follow(rs.camTarget.Position.Vec2, dir, dt)
+}
+
+// drawPlaceholder draws a magenta box over placeholderRect for an entity with
+// no image
+func (rs *RenderSystem) drawPlaceholder(e *Entity, screen *ebiten.Image) {
+	rect := placeholderRect(e)
+	if !rs.camera.IsVisible(rect) {
+		return
+	}
+	p := rs.toScreen(geom.Vec2{X: rect.X, Y: rect.Y})
+	zoom := rs.camera.Zoom
+	vector.FillRect(
+		screen,
+		float32(p.X), float32(p.Y),
+		float32(rect.W*zoom), float32(rect.H*zoom),
+		placeholderColour, false,
+	)
+}
+
+// placeholderRect returns the world rect of the placeholder drawn for an
+// entity with no image: its collision box, or a placeholderSize box at its
+// render offset if it has none
+func placeholderRect(e *Entity) geom.Rect {
+	rect, ok := e.CollisionRect()
+	if !ok || rect.W <= 0 || rect.H <= 0 {
+		rect = geom.Rect{
+			X: e.Position.X + e.Render.Offset.X,
+			Y: e.Position.Y + e.Render.Offset.Y,
+			W: placeholderSize,
+			H: placeholderSize,
+		}
+	}
+	return rect
+}
+
+// drawShadow draws a shadow ellipse for a sprite through the camera
+func (rs *RenderSystem) drawShadow(pos geom.Vec2, img *ebiten.Image, sh *Shadow, screen *ebiten.Image) {
+	if rs.shadowImg == nil {
+		rs.shadowImg = ebiten.NewImage(shadowImgSize, shadowImgSize)
+		r := float32(shadowImgSize) / 2
+		vector.FillCircle(rs.shadowImg, r, r, r, color.White, true)
+	}
+
+	size := geom.Size{W: img.Bounds().Dx(), H: img.Bounds().Dy()}
+	rect := sh.Rect(pos, size)
+	screenCoords := rs.toScreen(geom.Vec2{X: rect.X, Y: rect.Y})
+
+	opts := &rs.opts
+	opts.GeoM.Reset()
+	opts.ColorScale.Reset()
+	opts.GeoM.Scale(
+		rect.W/shadowImgSize*rs.camera.Zoom,
+		rect.H/shadowImgSize*rs.camera.Zoom,
+	)
+	opts.GeoM.Translate(screenCoords.X, screenCoords.Y)
+	opts.ColorScale.Scale(0, 0, 0, float32(sh.Alpha))
+	opts.Filter = ebiten.FilterLinear // soft edged at any scale
+	screen.DrawImage(rs.shadowImg, opts)
+}
+
+// VisibleTileRect returns the rect in tile coords the camera currently covers,
+// padded by a tile to the right and bottom, and widened left and down by the
+// map's TileOverhang so tiles larger than a cell that reach into view are
+// included. It is exactly the area tiles are drawn from, so gameplay can use
+// it to e.g. only animate water on screen. The rect is not clamped to the map
+// (see TileMap.ClampRect)
+func (rs *RenderSystem) VisibleTileRect() image.Rectangle {
+	return rs.visibleTileRect(geom.Vec2{})
+}
+
+// visibleTileRect is VisibleTileRect for a layer drawn shifted by shift world
+// px (see parallaxShift)
+func (rs *RenderSystem) visibleTileRect(shift geom.Vec2) image.Rectangle {
 	// Find the rectangle that the viewport covers as a rect on the tileMap
 	// by coverting world cooridanates to tile coords
-	offsetX := int(rs.camera.X)
-	offsetY := int(rs.camera.Y)
+	offsetX := int(math.Floor(rs.camera.X - shift.X))
+	offsetY := int(math.Floor(rs.camera.Y - shift.Y))
 
 	// Account for zoom when calculating visible area
 	viewportWorldW := int(float64(rs.camera.Viewport().W) / rs.camera.Zoom)
 	viewportWorldH := int(float64(rs.camera.Viewport().H) / rs.camera.Zoom)
 
-	tx0 := offsetX / rs.tileMap.TileWidth
-	tx1 := (offsetX+viewportWorldW)/rs.tileMap.TileWidth + 1
-	ty0 := offsetY / rs.tileMap.TileHeight
-	ty1 := (offsetY+viewportWorldH)/rs.tileMap.TileHeight + 1
+	// Parallax can put the view left of or above the map, so round down
+	// rather than towards zero
+	tx0 := floorDiv(offsetX, rs.tileMap.TileWidth)
+	tx1 := floorDiv(offsetX+viewportWorldW, rs.tileMap.TileWidth) + 1
+	ty0 := floorDiv(offsetY, rs.tileMap.TileHeight)
+	ty1 := floorDiv(offsetY+viewportWorldH, rs.tileMap.TileHeight) + 1
+
+	// Tiles larger than a cell reach up and right from cells outside the view
+	cols, rows := rs.tileMap.TileOverhang()
+	return image.Rect(tx0-cols, ty0, tx1, ty1+rows)
+}
 
-	viewRect := image.Rect(tx0, ty0, tx1, ty1)
+// floorDiv divides a by b > 0 rounding down
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
 
-	// Iterate layers and render
-	for layer := range rs.tileMap.NumLayers() {
-		err := rs.tileMap.ForEachIn(viewRect, layer, func(tx, ty, id int) {
-			worldCoords := geom.Vec2{
-				X: float64(tx * rs.tileMap.TileWidth),
-				Y: float64(ty * rs.tileMap.TileHeight),
-			}
-			img, err := rs.tileMap.GetImageById(id)
-			if err != nil {
-				panic(fmt.Sprintf("Failed to get tile image for ID %d at (%d, %d): %v", id, tx, ty, err))
-			}
-			if img != nil {
-				rs.drawToScreen(worldCoords, img, screen)
-			}
-		})
+// drawTileLayer draws the tiles of a layer that are in view, shifted by the
+// layer's parallax and faded by its opacity. Hidden layers aren't drawn
+func (rs *RenderSystem) drawTileLayer(layer int, screen *ebiten.Image) {
+	if !rs.tileMap.LayerVisible(layer) {
+		return
+	}
+	shift := rs.parallaxShift(rs.tileMap.LayerParallax(layer))
+	viewRect := rs.visibleTileRect(shift)
+	scale := rs.tileMap.TileScale()
+	alpha := rs.tileMap.LayerOpacity(layer)
+	err := rs.tileMap.ForEachIn(viewRect, layer, func(tx, ty, id int) {
+		img, err := rs.tileMap.GetImageById(id)
 		if err != nil {
-			panic(fmt.Sprintf("Failed to iterate tiles in layer %d: %v", layer, err))
+			panic(fmt.Sprintf("Failed to get tile image for ID %d at (%d, %d): %v", id, tx, ty, err))
 		}
+		if img != nil {
+			worldCoords := rs.tileMap.TileOrigin(tx, ty, img)
+			worldCoords.X += shift.X
+			worldCoords.Y += shift.Y
+			rs.drawToScreen(worldCoords, img, screen, scale, alpha, false)
+		}
+	})
+	if err != nil {
+		panic(fmt.Sprintf("Failed to iterate tiles in layer %d: %v", layer, err))
+	}
+}
+
+// drawImageLayers draws the map's image layers that sit under tile layer
+// before, offset by their parallax factor
+func (rs *RenderSystem) drawImageLayers(before int, screen *ebiten.Image) {
+	for _, il := range rs.tileMap.ImageLayers {
+		if il.Before != before {
+			continue
+		}
+		shift := rs.parallaxShift(il.Parallax)
+		worldCoords := geom.Vec2{X: il.Offset.X + shift.X, Y: il.Offset.Y + shift.Y}
+		rs.drawToScreen(worldCoords, il.Img, screen, 1, il.Opacity, false)
 	}
 }
 
+// parallaxShift returns how far to shift a layer with parallax factor p from
+// its world position. A parallax of p scrolls at p times the camera speed, the
+// same as shifting the layer by the camera position times (1 - p)
+func (rs *RenderSystem) parallaxShift(p geom.Vec2) geom.Vec2 {
+	return geom.Vec2{X: rs.camera.X * (1 - p.X), Y: rs.camera.Y * (1 - p.Y)}
+}
+
 func (rs *RenderSystem) drawToScreen(
 	worldCoords geom.Vec2,
 	img *ebiten.Image,
 	screen *ebiten.Image,
+	scale float64,
+	alpha float64,
+	flipX bool,
 ) {
-	screenCoords := rs.camera.Apply(worldCoords)
-	imgW := float64(img.Bounds().Dx()) * rs.camera.Zoom
-	imgH := float64(img.Bounds().Dy()) * rs.camera.Zoom
-	viewW := float64(rs.camera.Viewport().W)
-	viewH := float64(rs.camera.Viewport().H)
-
 	// Skip anything outside the visible screen
-	if screenCoords.X < -imgW || screenCoords.X > viewW ||
-		screenCoords.Y < -imgH || screenCoords.Y > viewH {
+	worldRect := geom.Rect{
+		X: worldCoords.X,
+		Y: worldCoords.Y,
+		W: float64(img.Bounds().Dx()) * scale,
+		H: float64(img.Bounds().Dy()) * scale,
+	}
+	if !rs.camera.IsVisible(worldRect) {
 		return
 	}
 
-	opts := &ebiten.DrawImageOptions{}
-	opts.GeoM.Scale(rs.camera.Zoom, rs.camera.Zoom)
+	screenCoords := rs.toScreen(worldCoords)
+	imgScale := scale * rs.camera.Zoom
+
+	opts := &rs.opts
+	opts.GeoM.Reset()
+	opts.ColorScale.Reset()
+	if flipX {
+		// Mirror within the image's own bounds so it stays in place
+		opts.GeoM.Scale(-1, 1)
+		opts.GeoM.Translate(float64(img.Bounds().Dx()), 0)
+	}
+	opts.GeoM.Scale(imgScale, imgScale)
 	opts.GeoM.Translate(screenCoords.X, screenCoords.Y)
+	opts.ColorScale.ScaleAlpha(float32(alpha))
+	opts.Filter = rs.Filter
 	screen.DrawImage(img, opts)
 }
 
+// TilePick is the tile under a screen position, see RenderSystem.PickTile
+type TilePick struct {
+	Tx, Ty int
+	Ids    []int // Global tile ID in each layer, by layer index (0 = empty)
+}
+
+// PickTile returns the tile under a screen position, e.g. the cursor, through
+// the camera (and the area given to DrawIn, if used). ok is false if the
+// position is outside the map
+func (rs *RenderSystem) PickTile(screenPos geom.Vec2) (pick TilePick, ok bool) {
+	world := rs.camera.Unapply(geom.Vec2{X: screenPos.X - rs.origin.X, Y: screenPos.Y - rs.origin.Y})
+	tx, ty := rs.tileMap.WorldToTile(world)
+	if tx < 0 || ty < 0 || tx >= rs.tileMap.MapWidth || ty >= rs.tileMap.MapHeight {
+		return TilePick{}, false
+	}
+
+	pick = TilePick{Tx: tx, Ty: ty, Ids: make([]int, rs.tileMap.NumLayers())}
+	for layer := range pick.Ids {
+		pick.Ids[layer] = rs.tileMap.TileIdAt(tx, ty, layer)
+	}
+	return pick, true
+}
+
+// toScreen converts world coords to screen coords within the area being drawn
+func (rs *RenderSystem) toScreen(worldCoords geom.Vec2) geom.Vec2 {
+	p := rs.camera.Apply(worldCoords)
+	return geom.Vec2{X: p.X + rs.origin.X, Y: p.Y + rs.origin.Y}
+}
+
 func NewRenderSystem(
 	ents *EntityManager,
 	cam *camera.Camera,
@@ -122,28 +447,72 @@ func NewRenderSystem(
 
 // MovementSystem handles updating position component for corresponding entity
 // based on movement data.
-// Checks whether a movement is possible by looking at tile map before moving
+// Checks whether a movement is possible by looking at tile map before moving.
+// The tile collision itself is done by a collision.Mover, which runs without
+// Ebiten
 type MovementSystem struct {
-	entities       *EntityManager
-	tileMap        *assetmgr.TileMap
-	collisionLayer int
+	entities *EntityManager
+	mover    collision.Mover
+	gravity  *Gravity // nil = top-down movement
 }
 
-func (ms *MovementSystem) Update(dt float64) {
-	tw := float64(ms.tileMap.TileWidth)
-	th := float64(ms.tileMap.TileHeight)
+// Gravity configures side-on platformer movement for the MovementSystem.
+// Entities then only use DesiredDir.X for walking, fall under gravity and
+// jump using MovementComponent.Jump when Grounded
+type Gravity struct {
+	Accel     float64 // Downward acceleration px/s²
+	JumpSpeed float64 // Upward speed px/s given by a jump
+	MaxFall   float64 // Terminal velocity px/s (0 = no limit)
+}
+
+// SetSolid sets which tiles in the collision layer block movement, e.g. only
+// some IDs in a layer mixing walls and decoration. solid is given the global
+// tile ID with Tiled's flip flags removed. Pass nil for the default where
+// every non-empty tile is solid
+func (ms *MovementSystem) SetSolid(solid func(tileId int) bool) {
+	ms.mover.Solid = solid
+}
+
+// SetCollisionGrid takes the collision map, layer and solid tiles from a
+// grid shared with other systems, replacing those given to NewMovementSystem
+// and SetSolid
+func (ms *MovementSystem) SetCollisionGrid(g *assetmgr.CollisionGrid) {
+	ms.mover.Map = g.Map()
+	ms.mover.Layer = g.Layer()
+	ms.mover.Solid = g.Solid
+}
+
+// SetGravity switches the system to platformer movement. Pass nil to go back
+// to top-down movement, which is the default
+func (ms *MovementSystem) SetGravity(g *Gravity) {
+	ms.gravity = g
+}
 
+func (ms *MovementSystem) Update(dt float64) {
 	ms.entities.Each(func(e *Entity) {
 		m := e.Movement
 		pos := e.Position
 
+		if pos != nil {
+			pos.PrevVec2 = pos.Vec2
+		}
 		if m == nil || pos == nil {
 			return
 		}
+		m.blocked = geom.Vec2I{}
+		// Checked before anything else so an entity whose collision was
+		// turned back on is moved out of a wall even while standing still
+		phasing := ms.phasing(e)
+
+		if ms.gravity != nil {
+			ms.updateGravity(e, dt, phasing)
+			return
+		}
 
 		// Check if there's any desired movement
 		if m.DesiredDir.X == 0 && m.DesiredDir.Y == 0 {
 			m.IsMoving = false
+			m.Velocity = geom.Vec2{}
 			return
 		}
 
@@ -154,22 +523,36 @@ func (ms *MovementSystem) Update(dt float64) {
 		// Calculate velocity
 		dx := dir.X * m.Speed * dt
 		dy := dir.Y * m.Speed * dt
+		if !geom.Finite(geom.Vec2{X: dx, Y: dy}) {
+			// A NaN/Inf speed or dt would poison the position for good
+			m.IsMoving = false
+			m.Velocity = geom.Vec2{}
+			return
+		}
 
 		// Store old position to detect actual movement
 		oldX, oldY := pos.X, pos.Y
 
 		// move X, then Y (axis-separated → natural sliding)
-		// If no collision component, move freely without collision checks
-		if e.Collision == nil {
+		// If no (or disabled) collision, move freely without collision checks
+		if phasing {
 			pos.X += dx
 			pos.Y += dy
 			m.IsMoving = true
-			m.FacingDir = m.DesiredDir
+			m.setFacing(m.DesiredDir)
+			m.Velocity = velocity(dx, dy, dt)
 			return
 		}
 
-		newX, newY := ms.resolveXAxis(pos.X, pos.Y, float64(e.Collision.Size.W), float64(e.Collision.Size.H), dx, tw, e.Collision.Offset)
-		newX, newY = ms.resolveYAxis(newX, newY, float64(e.Collision.Size.W), float64(e.Collision.Size.H), dy, th, e.Collision.Offset)
+		size := e.CollisionSize()
+		w, h := float64(size.W), float64(size.H)
+		newX, newY, hitX := ms.resolveXAxis(pos.X, pos.Y, w, h, dx, e.Collision)
+		var hitY bool
+		if !hitX || e.Collision.Response != CollisionStop {
+			newX, newY, hitY = ms.resolveYAxis(newX, newY, w, h, dy, e.Collision)
+		}
+		m.blocked = blockedDir(hitX, hitY, dx, dy)
+		respond(e, hitX, hitY)
 
 		// Update position
 		pos.X, pos.Y = newX, newY
@@ -180,104 +563,237 @@ func (ms *MovementSystem) Update(dt float64) {
 
 		// Update IsMoving based on whether position actually changed
 		m.IsMoving = (actualDX != 0 || actualDY != 0)
+		m.Velocity = velocity(actualDX, actualDY, dt)
 
 		// Update FacingDir to actual movement direction (or preserve if no movement)
 		if m.IsMoving {
 			// Convert actual movement to unit vector
+			var facing geom.Vec2I
 			if actualDX > 0 {
-				m.FacingDir.X = 1
+				facing.X = 1
 			} else if actualDX < 0 {
-				m.FacingDir.X = -1
-			} else {
-				m.FacingDir.X = 0
+				facing.X = -1
 			}
 
 			if actualDY > 0 {
-				m.FacingDir.Y = 1
+				facing.Y = 1
 			} else if actualDY < 0 {
-				m.FacingDir.Y = -1
-			} else {
-				m.FacingDir.Y = 0
+				facing.Y = -1
 			}
+			m.setFacing(facing)
 		}
 	})
 }
 
-// resolveXAxis moves along the X axis and clamps on collision.
-// It uses "predict and correct" logic:
-//  1. Calculate the new position (newX) after moving by dx
-//  2. Check if that position would overlap any tiles
-//  3. If yes, "push back" to the edge of the blocking tile
-//
-// Returns the resolved (x, y) position.
-func (ms *MovementSystem) resolveXAxis(posX, posY, w, h, dx, tileW float64, colOffset geom.Vec2) (float64, float64) {
-	// Try to move to the new X position
-	newX := posX + dx
+// updateGravity moves an entity in platformer mode: walking along X, falling
+// with gravity along Y and landing on (or bumping into) tiles. phasing is
+// whether it moves without colliding (see phasing)
+func (ms *MovementSystem) updateGravity(e *Entity, dt float64, phasing bool) {
+	m := e.Movement
+	pos := e.Position
+	g := ms.gravity
 
-	overlaps, err := ms.tileMap.OverlapsTiles(newX+colOffset.X, posY+colOffset.Y, w, h, ms.collisionLayer)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to check tile collision: %v", err))
-	}
-	if overlaps {
-		// We hit something! Need to push back to the edge of the blocking tile
-
-		if dx > 0 {
-			// Moving RIGHT - find the right edge of the entity and which tile column it's in
-			rightEdge := newX + w
-			blockingTileCol := math.Floor(rightEdge / tileW)
-			// Push back: left edge of blocking tile minus our width minus safety gap
-			newX = blockingTileCol*tileW - w - collisionEpsilon
-
-		} else if dx < 0 {
-			// Moving LEFT - find which tile column our left edge is in
-			blockingTileCol := math.Floor(newX / tileW)
-			// Push back: right edge of blocking tile plus safety gap
-			newX = (blockingTileCol+1)*tileW + collisionEpsilon
+	if m.Jump && m.Grounded {
+		m.FallSpeed = -g.JumpSpeed
+	}
+	m.Jump = false
+
+	m.FallSpeed += g.Accel * dt
+	if g.MaxFall > 0 && m.FallSpeed > g.MaxFall {
+		m.FallSpeed = g.MaxFall
+	}
+
+	dx := float64(m.DesiredDir.X) * m.Speed * dt
+	dy := m.FallSpeed * dt
+	if !geom.Finite(geom.Vec2{X: dx, Y: dy}) {
+		// A NaN/Inf speed or dt would poison the position for good
+		m.FallSpeed = 0
+		m.IsMoving = false
+		m.Velocity = geom.Vec2{}
+		return
+	}
+	oldX, oldY := pos.X, pos.Y
+
+	if phasing {
+		pos.X += dx
+		pos.Y += dy
+		m.Grounded = false
+	} else {
+		size := e.CollisionSize()
+		w, h := float64(size.W), float64(size.H)
+
+		var hitX, hitY bool
+		pos.X, pos.Y, hitX = ms.resolveXAxis(pos.X, pos.Y, w, h, dx, e.Collision)
+		pos.X, pos.Y, hitY = ms.resolveYAxis(pos.X, pos.Y, w, h, dy, e.Collision)
+
+		// Hitting a tile while falling means we landed, either way vertical movement stops
+		m.Grounded = hitY && dy > 0
+		if hitY {
+			if e.Collision.Response == CollisionBounce {
+				m.FallSpeed = -m.FallSpeed
+				m.Grounded = false
+			} else {
+				m.FallSpeed = 0
+			}
 		}
+		m.blocked = blockedDir(hitX, hitY, dx, dy)
+		respond(e, hitX, hitY)
 	}
 
-	return newX, posY
+	actualDX := pos.X - oldX
+	actualDY := pos.Y - oldY
+	m.IsMoving = actualDX != 0 || actualDY != 0
+	m.Velocity = velocity(actualDX, actualDY, dt)
+
+	// Only face left/right so falling doesn't lose the facing direction
+	if actualDX > 0 {
+		m.setFacing(geom.Vec2I{X: 1})
+	} else if actualDX < 0 {
+		m.setFacing(geom.Vec2I{X: -1})
+	}
+}
+
+// IsBlocked reports whether e was stopped by a tile moving in direction dir
+// (either axis of a diagonal counts) in the last Update, e.g. for wall
+// sliding. It is false once the entity stops pushing that way or moves off
+func (ms *MovementSystem) IsBlocked(e *Entity, dir geom.Vec2I) bool {
+	if e.Movement == nil {
+		return false
+	}
+	b := e.Movement.blocked
+	return (dir.X != 0 && sign(dir.X) == b.X) || (dir.Y != 0 && sign(dir.Y) == b.Y)
 }
 
-// resolveYAxis moves along the Y axis and clamps on collision.
-// It uses "predict and correct" logic:
-//  1. Calculate the new position (newY) after moving by dy
-//  2. Check if that position would overlap any tiles
-//  3. If yes, "push back" to the edge of the blocking tile
-//
-// Returns the resolved (x, y) position.
-func (ms *MovementSystem) resolveYAxis(posX, posY, w, h, dy, tileH float64, colOffset geom.Vec2) (float64, float64) {
-	// Try to move to the new Y position
-	newY := posY + dy
+// blockedDir returns the directions a move of dx, dy was blocked in
+func blockedDir(hitX, hitY bool, dx, dy float64) geom.Vec2I {
+	var b geom.Vec2I
+	if hitX {
+		b.X = signF(dx)
+	}
+	if hitY {
+		b.Y = signF(dy)
+	}
+	return b
+}
 
-	overlaps, err := ms.tileMap.OverlapsTiles(posX+colOffset.X, newY+colOffset.Y, w, h, ms.collisionLayer)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to check tile collision: %v", err))
-	}
-	if overlaps {
-		// We hit something! Need to push back to the edge of the blocking tile
-
-		if dy > 0 {
-			// Moving DOWN - find the bottom edge of the entity and which tile row it's in
-			bottomEdge := newY + h
-			blockingTileRow := math.Floor(bottomEdge / tileH)
-			// Push back: top edge of blocking tile minus our height minus safety gap
-			newY = blockingTileRow*tileH - h - collisionEpsilon
-
-		} else if dy < 0 {
-			// Moving UP - find which tile row our top edge is in
-			blockingTileRow := math.Floor(newY / tileH)
-			// Push back: bottom edge of blocking tile plus safety gap
-			newY = (blockingTileRow+1)*tileH + collisionEpsilon
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+func signF(v float64) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+// velocity converts a per frame displacement to px per second
+func velocity(dx, dy, dt float64) geom.Vec2 {
+	if dt <= 0 {
+		return geom.Vec2{}
+	}
+	return geom.Vec2{X: dx / dt, Y: dy / dt}
+}
+
+// phasing reports whether the entity moves without colliding, having no
+// CollisionComponent or having it Disabled. When collision has just been
+// turned back on with the entity inside a wall it is moved to the nearest
+// free tile first
+func (ms *MovementSystem) phasing(e *Entity) bool {
+	c := e.Collision
+	if c == nil {
+		return true
+	}
+	if c.Disabled {
+		c.phased = true
+		return true
+	}
+	if c.phased {
+		c.phased = false
+		ms.unstick(e)
+	}
+	return false
+}
+
+// unstick moves the entity so no part of its collision box is in a tile
+// blocking it in any layer of its LayerMask (see collision.Mover.NearestFree).
+// It reports false if the entity is stuck with nowhere clear nearby
+func (ms *MovementSystem) unstick(e *Entity) bool {
+	box, ok := e.CollisionRect()
+	if !ok {
+		return true
+	}
+	free, ok := ms.mover.NearestFree(box, e.Collision.LayerMask)
+	if !ok {
+		return false
+	}
+	e.Position.X += free.X - box.X
+	e.Position.Y += free.Y - box.Y
+	return true
+}
+
+// Spawn adds e to the entity manager, first moving it so its whole collision
+// box is clear of the tiles blocking it (in every layer of its LayerMask) if
+// it starts overlapping one, e.g. an enemy placed at a bad coordinate. If
+// there is nowhere clear nearby e isn't added and an error is returned.
+// Adding entities straight to the EntityManager skips the check
+func (ms *MovementSystem) Spawn(e *Entity) error {
+	if e.Position != nil && e.Collision != nil && !e.Collision.Disabled && !ms.unstick(e) {
+		return fmt.Errorf("no free tile to spawn %s near %v,%v", e.Name, e.Position.X, e.Position.Y)
+	}
+	ms.entities.Add(e)
+	return nil
+}
+
+// respond applies the entity's CollisionResponse after hitting a tile on the
+// X and/or Y axis. Sliding needs nothing doing, the resolvers already stopped
+// movement on the blocked axis
+func respond(e *Entity, hitX, hitY bool) {
+	m := e.Movement
+	switch e.Collision.Response {
+	case CollisionBounce:
+		if hitX {
+			m.DesiredDir.X = -m.DesiredDir.X
+		}
+		if hitY {
+			m.DesiredDir.Y = -m.DesiredDir.Y
+		}
+	case CollisionStop:
+		if hitX || hitY {
+			m.DesiredDir = geom.Vec2I{}
 		}
 	}
-	return posX, newY
+}
+
+// resolveXAxis moves the entity at posX, posY along the X axis by dx,
+// stopping its collision box (w x h at c.Offset) at blocking tiles (see
+// collision.Mover.MoveX). Returns the resolved (x, y) position and whether a
+// tile was hit.
+func (ms *MovementSystem) resolveXAxis(posX, posY, w, h, dx float64, c *CollisionComponent) (float64, float64, bool) {
+	box := geom.Rect{X: posX + c.Offset.X, Y: posY + c.Offset.Y, W: w, H: h}
+	boxX, hit := ms.mover.MoveX(box, dx, c.LayerMask)
+	return boxX - c.Offset.X, posY, hit
+}
+
+// resolveYAxis is resolveXAxis along the Y axis
+func (ms *MovementSystem) resolveYAxis(posX, posY, w, h, dy float64, c *CollisionComponent) (float64, float64, bool) {
+	box := geom.Rect{X: posX + c.Offset.X, Y: posY + c.Offset.Y, W: w, H: h}
+	boxY, hit := ms.mover.MoveY(box, dy, c.LayerMask)
+	return posX, boxY - c.Offset.Y, hit
 }
 
 func NewMovementSystem(ents *EntityManager, tiles *assetmgr.TileMap, collLayer int) *MovementSystem {
 	return &MovementSystem{
-		entities:       ents,
-		tileMap:        tiles,
-		collisionLayer: collLayer,
+		entities: ents,
+		mover:    collision.Mover{Map: tiles.Map, Layer: collLayer},
 	}
 }
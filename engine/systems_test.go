@@ -0,0 +1,976 @@
+package engine
+
+import (
+	"image"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/samredway/ebx/assetmgr"
+	"github.com/samredway/ebx/camera"
+	"github.com/samredway/ebx/collision"
+	"github.com/samredway/ebx/geom"
+	"github.com/samredway/ebx/internal/testutil"
+)
+
+// roomMap is a 6x4 map of 16px tiles with a single collision layer walled all
+// round, the inside spanning 16-80 by 16-48 px. It has no tilesets so nothing
+// needs a GPU
+func roomMap(t testing.TB) *assetmgr.TileMap {
+	t.Helper()
+	tm, err := assetmgr.NewTileMap(6, 4, 16, 16, [][]int{{
+		1, 1, 1, 1, 1, 1,
+		1, 0, 0, 0, 0, 1,
+		1, 0, 0, 0, 0, 1,
+		1, 1, 1, 1, 1, 1,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tm
+}
+
+// mover returns an entity with an 8x8 collision box at x, y moving at speed
+// px/s in dir
+func mover(x, y, speed float64, dir geom.Vec2I) *Entity {
+	return &Entity{
+		Name:      "mover",
+		Position:  &PositionComponent{Vec2: geom.Vec2{X: x, Y: y}},
+		Movement:  &MovementComponent{Speed: speed, DesiredDir: dir},
+		Collision: &CollisionComponent{Size: geom.Size{W: 8, H: 8}},
+	}
+}
+
+// near reports whether a and b are within float error of each other
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+
+func TestMovementSystemStopsAtWall(t *testing.T) {
+	ents := NewEntityManager()
+	e := mover(20, 20, 120, geom.Vec2I{X: 1})
+	ents.Add(e)
+	ms := NewMovementSystem(ents, roomMap(t), 0)
+
+	for range 60 {
+		ms.Update(1.0 / 60)
+	}
+
+	if want := 80 - 8 - collision.Epsilon; !near(e.Position.X, want) {
+		t.Errorf("X = %v, want flush with the wall at %v", e.Position.X, want)
+	}
+	if e.Position.Y != 20 {
+		t.Errorf("Y = %v, want unchanged 20", e.Position.Y)
+	}
+	if e.Movement.IsMoving {
+		t.Error("IsMoving is true while pressed against the wall")
+	}
+}
+
+func TestGravityFallLandJump(t *testing.T) {
+	ents := NewEntityManager()
+	e := mover(20, 16, 100, geom.Vec2I{})
+	ents.Add(e)
+	ms := NewMovementSystem(ents, roomMap(t), 0)
+	ms.SetGravity(&Gravity{Accel: 800, JumpSpeed: 250, MaxFall: 120})
+	const dt = 1.0 / 60
+
+	// Falling
+	ms.Update(dt)
+	if e.Position.Y <= 16 || e.Movement.FallSpeed <= 0 {
+		t.Fatalf("after one update Y = %v, FallSpeed = %v, want falling", e.Position.Y, e.Movement.FallSpeed)
+	}
+	if e.Movement.Grounded {
+		t.Fatal("Grounded while falling")
+	}
+
+	// Landing on the floor, never faster than MaxFall
+	for range 60 {
+		ms.Update(dt)
+		if e.Movement.FallSpeed > 120 {
+			t.Fatalf("FallSpeed %v is over MaxFall", e.Movement.FallSpeed)
+		}
+	}
+	if !e.Movement.Grounded {
+		t.Fatal("not Grounded after landing")
+	}
+	if want := 48 - 8 - collision.Epsilon; !near(e.Position.Y, want) {
+		t.Errorf("Y = %v, want resting on the floor at %v", e.Position.Y, want)
+	}
+	if e.Movement.FallSpeed != 0 {
+		t.Errorf("FallSpeed = %v after landing, want 0", e.Movement.FallSpeed)
+	}
+
+	// Standing stays Grounded rather than sinking into the floor every frame
+	for range 10 {
+		ms.Update(dt)
+		if !e.Movement.Grounded {
+			t.Fatalf("lost Grounded standing on the floor at Y = %v", e.Position.Y)
+		}
+	}
+
+	// Jumping
+	landed := e.Position.Y
+	e.Movement.Jump = true
+	ms.Update(dt)
+	if e.Position.Y >= landed || e.Movement.FallSpeed >= 0 {
+		t.Errorf("after jumping Y = %v, FallSpeed = %v, want moving up", e.Position.Y, e.Movement.FallSpeed)
+	}
+	if e.Movement.Grounded || e.Movement.Jump {
+		t.Errorf("Grounded = %v, Jump = %v after jumping, want both false", e.Movement.Grounded, e.Movement.Jump)
+	}
+
+	// No jumping in mid air
+	fall := e.Movement.FallSpeed
+	e.Movement.Jump = true
+	ms.Update(dt)
+	if e.Movement.FallSpeed < fall {
+		t.Errorf("mid air jump changed FallSpeed from %v to %v", fall, e.Movement.FallSpeed)
+	}
+}
+
+// spriteScene returns a RenderSystem over roomMap that only draws entities,
+// with a camera showing the whole map, and its entity manager
+func spriteScene(tb testing.TB) (*RenderSystem, *EntityManager) {
+	ents := NewEntityManager()
+	cam := camera.NewCamera(geom.Size{W: 96, H: 64}, image.Rect(0, 0, 96, 64))
+	target := &Entity{Position: &PositionComponent{}}
+	rs := NewRenderSystem(ents, cam, target, roomMap(tb))
+	rs.DrawOrder = []DrawPass{{Kind: PassEntities}}
+	return rs, ents
+}
+
+func TestDrawSpritesDontInheritOptions(t *testing.T) {
+	rs, ents := spriteScene(t)
+	img := ebiten.NewImage(8, 8)
+	ents.Add(&Entity{
+		Position: &PositionComponent{Vec2: geom.Vec2{X: 20, Y: 20}},
+		Render: &RenderComponent{
+			Img: img, FadeFrom: img, FadeAlpha: 0.3, FlipX: true,
+			Shadow: &Shadow{Size: geom.Size{W: 8, H: 4}, Alpha: 0.5},
+		},
+	})
+	ents.Add(&Entity{
+		Position: &PositionComponent{Vec2: geom.Vec2{X: 40, Y: 20}},
+		Render:   &RenderComponent{Img: img},
+	})
+
+	rs.Draw(ebiten.NewImage(96, 64))
+
+	// The last sprite is drawn exactly as it would be with fresh options
+	var want ebiten.DrawImageOptions
+	want.GeoM.Translate(40, 20)
+	if rs.opts.GeoM != want.GeoM {
+		t.Errorf("GeoM = %v, want %v", rs.opts.GeoM.String(), want.GeoM.String())
+	}
+	if rs.opts.ColorScale != want.ColorScale {
+		t.Errorf("ColorScale = %v, want %v", rs.opts.ColorScale, want.ColorScale)
+	}
+	if rs.opts.Filter != want.Filter {
+		t.Errorf("Filter = %v, want %v", rs.opts.Filter, want.Filter)
+	}
+}
+
+func BenchmarkDrawIdenticalSprites(b *testing.B) {
+	rs, ents := spriteScene(b)
+	img := ebiten.NewImage(8, 8)
+	for i := range 200 {
+		ents.Add(&Entity{
+			Position: &PositionComponent{Vec2: geom.Vec2{X: float64(i % 88), Y: float64(i % 56)}},
+			Render:   &RenderComponent{Img: img},
+		})
+	}
+	screen := ebiten.NewImage(96, 64)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		rs.Draw(screen)
+	}
+}
+
+func TestRenderSystemUpdateMovesCamera(t *testing.T) {
+	ents := NewEntityManager()
+	cam := camera.NewCamera(geom.Size{W: 32, H: 32}, image.Rect(0, 0, 96, 64))
+	cam.LookAhead = 8
+	target := mover(40, 30, 60, geom.Vec2I{X: 1})
+	target.Movement.Velocity = geom.Vec2{X: 60}
+	rs := NewRenderSystem(ents, cam, target, roomMap(t))
+	rs.DrawOrder = []DrawPass{{Kind: PassEntities}}
+	screen := ebiten.NewImage(32, 32)
+
+	rs.Update(1.0 / 60)
+	after := cam.Vec2
+	if after == (geom.Vec2{}) {
+		t.Fatal("Update didn't move the camera to its target")
+	}
+
+	// Drawing any number of times leaves the camera where Update put it
+	for range 3 {
+		rs.Draw(screen)
+	}
+	if cam.Vec2 != after {
+		t.Errorf("Draw moved the camera from %v to %v", after, cam.Vec2)
+	}
+
+	// The look-ahead eases in by dt, not per draw
+	rs.Update(1.0 / 60)
+	if cam.X <= after.X {
+		t.Errorf("camera X = %v, want leading the target past %v", cam.X, after.X)
+	}
+}
+
+func TestMovementSystemNegativeOffset(t *testing.T) {
+	tests := []struct {
+		name  string
+		dir   geom.Vec2I
+		check func(box geom.Rect) (got, want float64)
+	}{
+		{"right", geom.Vec2I{X: 1}, func(b geom.Rect) (float64, float64) { return b.X + b.W, 80 - collision.Epsilon }},
+		{"left", geom.Vec2I{X: -1}, func(b geom.Rect) (float64, float64) { return b.X, 16 + collision.Epsilon }},
+		{"down", geom.Vec2I{Y: 1}, func(b geom.Rect) (float64, float64) { return b.Y + b.H, 48 - collision.Epsilon }},
+		{"up", geom.Vec2I{Y: -1}, func(b geom.Rect) (float64, float64) { return b.Y, 16 + collision.Epsilon }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ents := NewEntityManager()
+			// The box extends up and left of the position, starting at 32, 28
+			e := mover(40, 36, 120, tt.dir)
+			e.Collision.Offset = geom.Vec2{X: -8, Y: -8}
+			ents.Add(e)
+			ms := NewMovementSystem(ents, roomMap(t), 0)
+
+			for range 60 {
+				ms.Update(1.0 / 60)
+			}
+
+			box, _ := e.CollisionRect()
+			if got, want := tt.check(box); !near(got, want) {
+				t.Errorf("box %v edge at %v, want flush with the wall at %v", box, got, want)
+			}
+		})
+	}
+}
+
+func TestPickTileZoomed(t *testing.T) {
+	ents := NewEntityManager()
+	cam := camera.NewCamera(geom.Size{W: 48, H: 32}, image.Rect(0, 0, 96, 64))
+	cam.SetZoom(2)
+	cam.CentreOn(geom.Vec2{X: 48, Y: 32})
+	rs := NewRenderSystem(ents, cam, &Entity{Position: &PositionComponent{}}, roomMap(t))
+
+	// At 2x zoom a screen px is half a world px from the camera's top left
+	screen := geom.Vec2{X: 10, Y: 6}
+	world := geom.Vec2{X: cam.X + screen.X/2, Y: cam.Y + screen.Y/2}
+	pick, ok := rs.PickTile(screen)
+	if !ok {
+		t.Fatalf("PickTile(%v) not ok", screen)
+	}
+	wantTx, wantTy := int(world.X)/16, int(world.Y)/16
+	if pick.Tx != wantTx || pick.Ty != wantTy {
+		t.Errorf("picked tile (%d, %d) for world %v, want (%d, %d)", pick.Tx, pick.Ty, world, wantTx, wantTy)
+	}
+
+	// The top left wall tile
+	pick, ok = rs.PickTile(geom.Vec2{X: (8 - cam.X) * 2, Y: (8 - cam.Y) * 2})
+	if !ok || pick.Tx != 0 || pick.Ty != 0 || len(pick.Ids) != 1 || pick.Ids[0] != 1 {
+		t.Errorf("PickTile = %+v, %v, want wall tile (0, 0) with Ids [1]", pick, ok)
+	}
+
+	if _, ok := rs.PickTile(geom.Vec2{X: -1000, Y: 0}); ok {
+		t.Error("PickTile outside the map is ok")
+	}
+}
+
+func TestDrawTileLayerVisibilityAndOpacity(t *testing.T) {
+	all := func(id int) []int {
+		data := make([]int, 6*4)
+		for i := range data {
+			data[i] = id
+		}
+		return data
+	}
+	fx := testutil.MapFixture{
+		Width: 6, Height: 4, TileW: 16, TileH: 16, Columns: 2, Rows: 2,
+		// The hidden layer's tile ID isn't in the tileset, so drawing it panics
+		Layers:     [][]int{all(1), all(99)},
+		LayerAttrs: []string{`opacity="0.5"`, `visible="0"`},
+	}
+	tm, err := assetmgr.NewTileMapFromTmx(fx.FS(), testutil.MapPath, assetmgr.NewAssets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cam := camera.NewCamera(geom.Size{W: 96, H: 64}, image.Rect(0, 0, 96, 64))
+	rs := NewRenderSystem(NewEntityManager(), cam, &Entity{Position: &PositionComponent{}}, tm)
+
+	rs.Draw(ebiten.NewImage(96, 64))
+
+	if got := rs.opts.ColorScale.A(); got != 0.5 {
+		t.Errorf("tiles drawn with alpha %v, want the layer opacity 0.5", got)
+	}
+}
+
+func TestParallaxVisibleTileRect(t *testing.T) {
+	fx := testutil.MapFixture{
+		Width: 20, Height: 10, TileW: 16, TileH: 16, Columns: 2, Rows: 2,
+		Layers:     [][]int{make([]int, 20*10), make([]int, 20*10)},
+		LayerAttrs: []string{"", `parallaxx="0.5" parallaxy="0.5"`},
+	}
+	tm, err := assetmgr.NewTileMapFromTmx(fx.FS(), testutil.MapPath, assetmgr.NewAssets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cam := camera.NewCameraAt(geom.Size{W: 64, H: 32}, image.Rect(0, 0, 320, 160), geom.Vec2{X: 200, Y: 100})
+	rs := NewRenderSystem(NewEntityManager(), cam, &Entity{Position: &PositionComponent{}}, tm)
+
+	// Camera top left is (168, 84)
+	world := image.Rect(10, 5, 15, 8)
+	if got := rs.VisibleTileRect(); got != world {
+		t.Errorf("VisibleTileRect = %v, want %v", got, world)
+	}
+	if got := rs.visibleTileRect(rs.parallaxShift(tm.LayerParallax(0))); got != world {
+		t.Errorf("factor 1 layer sees %v, want the world rect %v", got, world)
+	}
+
+	// At half speed the layer has only scrolled to (84, 42)
+	if got, want := tm.LayerParallax(1), (geom.Vec2{X: 0.5, Y: 0.5}); got != want {
+		t.Fatalf("LayerParallax(1) = %v, want %v", got, want)
+	}
+	if got, want := rs.visibleTileRect(rs.parallaxShift(tm.LayerParallax(1))), image.Rect(5, 2, 10, 5); got != want {
+		t.Errorf("factor 0.5 layer sees %v, want %v", got, want)
+	}
+}
+
+func TestIsBlocked(t *testing.T) {
+	ents := NewEntityManager()
+	e := mover(60, 20, 120, geom.Vec2I{X: 1})
+	ents.Add(e)
+	ms := NewMovementSystem(ents, roomMap(t), 0)
+
+	// Pressed into the right wall
+	for range 30 {
+		ms.Update(1.0 / 60)
+	}
+	if !ms.IsBlocked(e, geom.Vec2I{X: 1}) {
+		t.Error("not blocked pressing into the right wall")
+	}
+	if !ms.IsBlocked(e, geom.Vec2I{X: 1, Y: 1}) {
+		t.Error("not blocked down-right, the X axis is against the wall")
+	}
+	if ms.IsBlocked(e, geom.Vec2I{X: -1}) || ms.IsBlocked(e, geom.Vec2I{Y: 1}) {
+		t.Error("blocked in a direction with no wall")
+	}
+
+	// Moving away resets it the next frame
+	e.Movement.DesiredDir = geom.Vec2I{X: -1}
+	ms.Update(1.0 / 60)
+	if ms.IsBlocked(e, geom.Vec2I{X: 1}) {
+		t.Error("still blocked after moving away from the wall")
+	}
+}
+
+func TestFlipXMirrorsOffsetAboutBox(t *testing.T) {
+	tests := []struct {
+		name      string
+		offset    float64 // Render.Offset.X
+		collision *CollisionComponent
+		want      float64 // Sprite left edge from the position when flipped
+	}{
+		// A 16px sprite centred on an 8px box stays centred
+		{"centred", -4, &CollisionComponent{Size: geom.Size{W: 8, H: 8}}, -4},
+		// 2px left of the box's left edge flips to 6px right of its right edge
+		{"off centre", -2, &CollisionComponent{Size: geom.Size{W: 8, H: 8}}, -6},
+		{"box offset", 0, &CollisionComponent{Size: geom.Size{W: 8, H: 8}, Offset: geom.Vec2{X: 4}}, 0},
+		{"no box", 3, nil, -3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, ents := spriteScene(t)
+			ents.Add(&Entity{
+				Position:  &PositionComponent{Vec2: geom.Vec2{X: 40, Y: 20}},
+				Collision: tt.collision,
+				Render: &RenderComponent{
+					Img:    ebiten.NewImage(16, 16),
+					Offset: geom.Vec2{X: tt.offset},
+					FlipX:  true,
+				},
+			})
+
+			rs.Draw(ebiten.NewImage(96, 64))
+
+			// The flip adds the image width to the translation
+			left := rs.opts.GeoM.Element(0, 2) - 16
+			if got := left - 40; !near(got, tt.want) {
+				t.Errorf("flipped sprite drawn at offset %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayerMaskGhostAndBoat(t *testing.T) {
+	// Layer 0 is the room's walls, layer 1 is land with water from x 48 on
+	tm, err := assetmgr.NewTileMap(6, 4, 16, 16, [][]int{
+		roomMap(t).Layers[0],
+		{
+			1, 1, 1, 0, 0, 0,
+			1, 1, 1, 0, 0, 0,
+			1, 1, 1, 0, 0, 0,
+			1, 1, 1, 0, 0, 0,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ents := NewEntityManager()
+	ghost := mover(60, 20, 120, geom.Vec2I{X: 1})
+	ghost.Collision.LayerMask = []int{}
+	boat := mover(60, 36, 120, geom.Vec2I{X: -1})
+	boat.Collision.LayerMask = []int{1}
+	ents.Add(ghost)
+	ents.Add(boat)
+	ms := NewMovementSystem(ents, tm, 0)
+
+	for range 60 {
+		ms.Update(1.0 / 60)
+	}
+
+	// The ghost goes through the wall but stops at the edge of the map
+	if want := 96 - 8 - collision.Epsilon; !near(ghost.Position.X, want) {
+		t.Errorf("ghost X = %v, want through the wall to the map edge at %v", ghost.Position.X, want)
+	}
+	// The boat ignores the walls layer and stops at the shore
+	if want := 48 + collision.Epsilon; !near(boat.Position.X, want) {
+		t.Errorf("boat X = %v, want stopped at the shore at %v", boat.Position.X, want)
+	}
+}
+
+func TestSpawnRelocatesOutOfWall(t *testing.T) {
+	ents := NewEntityManager()
+	ms := NewMovementSystem(ents, roomMap(t), 0)
+
+	// Inside the top left wall tile
+	e := mover(4, 4, 0, geom.Vec2I{})
+	if err := ms.Spawn(e); err != nil {
+		t.Fatal(err)
+	}
+	box, _ := e.CollisionRect()
+	if want := (geom.Rect{X: 20, Y: 20, W: 8, H: 8}); box != want {
+		t.Errorf("spawned with box %v, want moved to the nearest free tile %v", box, want)
+	}
+	if len(ents.entities) != 1 {
+		t.Error("spawned entity not added")
+	}
+
+	// No free tile in a solid map
+	solid, err := assetmgr.NewTileMap(2, 2, 16, 16, [][]int{{1, 1, 1, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms = NewMovementSystem(ents, solid, 0)
+	if err := ms.Spawn(mover(4, 4, 0, geom.Vec2I{})); err == nil {
+		t.Error("Spawn in a map with no free tile didn't error")
+	}
+}
+
+func TestDrawInTwoCameras(t *testing.T) {
+	ents := NewEntityManager()
+	sprite := &Entity{
+		Position: &PositionComponent{Vec2: geom.Vec2{X: 40, Y: 20}},
+		Render:   &RenderComponent{Img: ebiten.NewImage(8, 8)},
+	}
+	p1 := &Entity{Position: &PositionComponent{Vec2: geom.Vec2{X: 10, Y: 30}}}
+	p2 := &Entity{Position: &PositionComponent{Vec2: geom.Vec2{X: 70, Y: 30}}}
+	ents.Add(sprite)
+	world := image.Rect(0, 0, 96, 64)
+	tm := roomMap(t)
+	left := NewRenderSystem(ents, camera.NewCamera(geom.Size{W: 96, H: 64}, world), p1, tm)
+	right := NewRenderSystem(ents, camera.NewCamera(geom.Size{W: 96, H: 64}, world), p2, tm)
+	screen := ebiten.NewImage(96, 64)
+	leftArea, rightArea := image.Rect(0, 0, 48, 64), image.Rect(48, 0, 96, 64)
+
+	tests := []struct {
+		name string
+		rs   *RenderSystem
+		area image.Rectangle
+		cam  geom.Vec2 // Camera top left
+	}{
+		{"left", left, leftArea, geom.Vec2{X: 0, Y: 0}},
+		{"right", right, rightArea, geom.Vec2{X: 46, Y: 0}},
+	}
+	// Each camera follows its own player in a half of the screen. The first
+	// DrawIn sizes the viewport for the next frame's Update
+	for range 2 {
+		for _, tt := range tests {
+			tt.rs.DrawOrder = []DrawPass{{Kind: PassEntities}}
+			tt.rs.Update(1.0 / 60)
+			tt.rs.DrawIn(screen, tt.area)
+		}
+	}
+	for _, tt := range tests {
+		tt.rs.DrawIn(screen, tt.area)
+		cam := tt.rs.camera
+		if cam.Viewport() != (geom.Size{W: 48, H: 64}) || cam.Vec2 != tt.cam {
+			t.Errorf("%s camera at %v with viewport %v, want %v and 48x64", tt.name, cam.Vec2, cam.Viewport(), tt.cam)
+		}
+		// The sprite lands in the camera's half of the screen
+		wantX := float64(tt.area.Min.X) + 40 - tt.cam.X
+		if got := tt.rs.opts.GeoM.Element(0, 2); !near(got, wantX) {
+			t.Errorf("%s camera drew the sprite at screen X %v, want %v", tt.name, got, wantX)
+		}
+	}
+	if left.opts.GeoM == right.opts.GeoM {
+		t.Errorf("both cameras drew the sprite with transform %v", left.opts.GeoM.String())
+	}
+}
+
+func TestShadowAtSpriteFeet(t *testing.T) {
+	sh := &Shadow{Size: geom.Size{W: 12, H: 4}, Offset: geom.Vec2{X: 1, Y: -2}, Alpha: 0.4}
+	// A 16x24 sprite at (40, 20) has its feet centred at (48, 44)
+	rect := sh.Rect(geom.Vec2{X: 40, Y: 20}, geom.Size{W: 16, H: 24})
+	if want := (geom.Rect{X: 43, Y: 40, W: 12, H: 4}); rect != want {
+		t.Fatalf("shadow rect %v, want %v", rect, want)
+	}
+
+	// Drawn through a zoomed camera
+	rs, _ := spriteScene(t)
+	rs.camera.SetZoom(2)
+	rs.camera.X, rs.camera.Y = 30, 10
+	rs.drawShadow(geom.Vec2{X: 40, Y: 20}, ebiten.NewImage(16, 24), sh, ebiten.NewImage(96, 64))
+
+	var want ebiten.GeoM
+	want.Scale(12.0/shadowImgSize*2, 4.0/shadowImgSize*2)
+	want.Translate((43-30)*2, (40-10)*2)
+	if rs.opts.GeoM != want {
+		t.Errorf("shadow GeoM = %v, want %v", rs.opts.GeoM.String(), want.String())
+	}
+	if got := rs.opts.ColorScale.A(); !near(float64(got), 0.4) {
+		t.Errorf("shadow alpha %v, want 0.4", got)
+	}
+
+	// The sprite is drawn after, over its shadow
+	rs.entities.Add(&Entity{
+		Position: &PositionComponent{Vec2: geom.Vec2{X: 40, Y: 20}},
+		Render:   &RenderComponent{Img: ebiten.NewImage(16, 24), Shadow: sh},
+	})
+	rs.Draw(ebiten.NewImage(96, 64))
+	var sprite ebiten.GeoM
+	sprite.Scale(2, 2)
+	sprite.Translate((40-30)*2, (20-10)*2)
+	if rs.opts.GeoM != sprite || rs.opts.ColorScale.A() != 1 {
+		t.Errorf("last draw GeoM %v alpha %v, want the sprite %v at 1",
+			rs.opts.GeoM.String(), rs.opts.ColorScale.A(), sprite.String())
+	}
+}
+
+func TestStrikeFrameOffset(t *testing.T) {
+	rs, ents := spriteScene(t)
+	rs.camera.SetZoom(2)
+	rs.camera.X, rs.camera.Y = 30, 10
+	anim := Animation{Frames: []*ebiten.Image{ebiten.NewImage(16, 16), ebiten.NewImage(16, 16)}}
+	offsets := []geom.Vec2{{}, {X: 6, Y: -1}} // The second frame is the strike
+	e := &Entity{
+		Position: &PositionComponent{Vec2: geom.Vec2{X: 40, Y: 20}},
+		Render:   &RenderComponent{},
+	}
+	ents.Add(e)
+	screen := ebiten.NewImage(96, 64)
+
+	for frame, offset := range offsets {
+		anim.Apply(e.Render, frame)
+		e.Render.Offset = offset
+		rs.Draw(screen)
+
+		// The offset is in source px, so scaled by the zoom
+		x, y := rs.opts.GeoM.Element(0, 2), rs.opts.GeoM.Element(1, 2)
+		wantX, wantY := (40+offset.X-30)*2, (20+offset.Y-10)*2
+		if !near(x, wantX) || !near(y, wantY) {
+			t.Errorf("frame %d drawn at (%v, %v), want (%v, %v)", frame, x, y, wantX, wantY)
+		}
+	}
+}
+
+func TestFilterOnDrawOptions(t *testing.T) {
+	all := make([]int, 6*4)
+	for i := range all {
+		all[i] = 1
+	}
+	fx := testutil.MapFixture{
+		Width: 6, Height: 4, TileW: 16, TileH: 16, Columns: 2, Rows: 2,
+		Layers: [][]int{all},
+	}
+	tm, err := assetmgr.NewTileMapFromTmx(fx.FS(), testutil.MapPath, assetmgr.NewAssets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ents := NewEntityManager()
+	ents.Add(&Entity{
+		Position: &PositionComponent{Vec2: geom.Vec2{X: 20, Y: 20}},
+		Render:   &RenderComponent{Img: ebiten.NewImage(8, 8)},
+	})
+	cam := camera.NewCamera(geom.Size{W: 48, H: 32}, image.Rect(0, 0, 96, 64))
+	cam.SetZoom(2)
+	rs := NewRenderSystem(ents, cam, &Entity{Position: &PositionComponent{}}, tm)
+	screen := ebiten.NewImage(48, 32)
+
+	tiles := []DrawPass{{Kind: PassTileLayer}}
+	sprites := []DrawPass{{Kind: PassEntities}}
+	for _, filter := range []ebiten.Filter{ebiten.FilterNearest, ebiten.FilterLinear} {
+		if filter == ebiten.FilterLinear {
+			rs.Filter = filter
+		}
+		for name, order := range map[string][]DrawPass{"tiles": tiles, "sprites": sprites} {
+			rs.opts.Filter = -1
+			rs.DrawOrder = order
+			rs.Draw(screen)
+			if rs.opts.Filter != filter {
+				t.Errorf("%s drawn with filter %v, want %v", name, rs.opts.Filter, filter)
+			}
+		}
+	}
+}
+
+func TestDrawOrder(t *testing.T) {
+	tm, err := assetmgr.NewTileMap(6, 4, 16, 16, [][]int{make([]int, 24), make([]int, 24)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DrawPass{
+		{Kind: PassImageLayers, Layer: 0}, {Kind: PassTileLayer, Layer: 0},
+		{Kind: PassImageLayers, Layer: 1}, {Kind: PassTileLayer, Layer: 1},
+		{Kind: PassImageLayers, Layer: 2},
+		{Kind: PassEntities},
+		{Kind: PassFlashes},
+	}
+	if got := DefaultDrawOrder(tm); !slices.Equal(got, want) {
+		t.Errorf("DefaultDrawOrder = %v, want %v", got, want)
+	}
+
+	// Which pass drew last shows in the reused draw options: the half opaque
+	// tile layer or the fully opaque sprite
+	all := make([]int, 6*4)
+	for i := range all {
+		all[i] = 1
+	}
+	fx := testutil.MapFixture{
+		Width: 6, Height: 4, TileW: 16, TileH: 16, Columns: 2, Rows: 2,
+		Layers:     [][]int{all},
+		LayerAttrs: []string{`opacity="0.5"`},
+	}
+	tm, err = assetmgr.NewTileMapFromTmx(fx.FS(), testutil.MapPath, assetmgr.NewAssets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ents := NewEntityManager()
+	ents.Add(&Entity{
+		Position: &PositionComponent{Vec2: geom.Vec2{X: 20, Y: 20}},
+		Render:   &RenderComponent{Img: ebiten.NewImage(8, 8)},
+	})
+	cam := camera.NewCamera(geom.Size{W: 96, H: 64}, image.Rect(0, 0, 96, 64))
+	rs := NewRenderSystem(ents, cam, &Entity{Position: &PositionComponent{}}, tm)
+	screen := ebiten.NewImage(96, 64)
+
+	tests := []struct {
+		name      string
+		order     []DrawPass
+		wantAlpha float32
+	}{
+		{"default, tiles then entities", nil, 1},
+		{"entities under tiles", []DrawPass{{Kind: PassEntities}, {Kind: PassTileLayer}}, 0.5},
+		{"tiles then entities", []DrawPass{{Kind: PassTileLayer}, {Kind: PassEntities}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs.DrawOrder = tt.order
+			rs.Draw(screen)
+			if got := rs.opts.ColorScale.A(); got != tt.wantAlpha {
+				t.Errorf("last draw alpha %v, want %v", got, tt.wantAlpha)
+			}
+		})
+	}
+}
+
+func TestNilImagePlaceholder(t *testing.T) {
+	boxed := &Entity{
+		Position:  &PositionComponent{Vec2: geom.Vec2{X: 20, Y: 20}},
+		Collision: &CollisionComponent{Size: geom.Size{W: 8, H: 12}, Offset: geom.Vec2{X: 2}},
+		Render:    &RenderComponent{},
+	}
+	bare := &Entity{
+		Position: &PositionComponent{Vec2: geom.Vec2{X: 40, Y: 20}},
+		Render:   &RenderComponent{Offset: geom.Vec2{Y: -4}},
+	}
+	if got, want := placeholderRect(boxed), (geom.Rect{X: 22, Y: 20, W: 8, H: 12}); got != want {
+		t.Errorf("placeholder for a boxed entity at %v, want its collision box %v", got, want)
+	}
+	if got, want := placeholderRect(bare), (geom.Rect{X: 40, Y: 16, W: 16, H: 16}); got != want {
+		t.Errorf("placeholder for an entity with no box at %v, want %v", got, want)
+	}
+
+	// Drawing either, shown or hidden, doesn't crash or draw a sprite
+	for _, hide := range []bool{false, true} {
+		rs, ents := spriteScene(t)
+		rs.HidePlaceholders = hide
+		ents.Add(boxed)
+		ents.Add(bare)
+		rs.Draw(ebiten.NewImage(96, 64))
+		if rs.opts.GeoM != (ebiten.GeoM{}) {
+			t.Errorf("HidePlaceholders %v: a sprite was drawn for nil images", hide)
+		}
+	}
+}
+
+func TestPhasingThroughWallAndUnstick(t *testing.T) {
+	// A 10x4 room split by a wall in column 4
+	tm, err := assetmgr.NewTileMap(10, 4, 16, 16, [][]int{{
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 0, 0, 0, 1, 0, 0, 0, 0, 1,
+		1, 0, 0, 0, 1, 0, 0, 0, 0, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ents := NewEntityManager()
+	e := mover(40, 20, 60, geom.Vec2I{X: 1})
+	e.Collision.Disabled = true
+	ents.Add(e)
+	ms := NewMovementSystem(ents, tm, 0)
+
+	// Straight through the wall at 64-80
+	for range 40 {
+		ms.Update(1.0 / 60)
+	}
+	if !near(e.Position.X, 80) {
+		t.Fatalf("phasing entity at X %v, want through the wall at 80", e.Position.X)
+	}
+
+	// Re-enabled while standing in the wall, it is moved to the nearest tile
+	// its whole box fits in, without having to move first
+	e.Position.X = 66
+	ms.Update(1.0 / 60)
+	e.Movement.DesiredDir = geom.Vec2I{}
+	e.Collision.Disabled = false
+	ms.Update(1.0 / 60)
+	box, _ := e.CollisionRect()
+	if want := (geom.Rect{X: 52, Y: 20, W: 8, H: 8}); box != want {
+		t.Errorf("after re-enabling collision box at %v, want moved clear to %v", box, want)
+	}
+
+	// Collision is back on
+	e.Movement.DesiredDir = geom.Vec2I{X: 1}
+	for range 30 {
+		ms.Update(1.0 / 60)
+	}
+	if e.Position.X > 56 {
+		t.Errorf("at X %v after re-enabling, want stopped by the wall at 56", e.Position.X)
+	}
+}
+
+func TestCollisionResponseBounceAndStop(t *testing.T) {
+	tm := roomMap(t)
+
+	// Heading down-right into the right wall at 80 before reaching the floor
+	ents := NewEntityManager()
+	bounce := mover(60, 20, 60, geom.Vec2I{X: 1, Y: 1})
+	bounce.Collision.Response = CollisionBounce
+	stop := mover(60, 20, 60, geom.Vec2I{X: 1, Y: 1})
+	stop.Name = "stop"
+	stop.Collision.Response = CollisionStop
+	ents.Add(bounce)
+	ents.Add(stop)
+	ms := NewMovementSystem(ents, tm, 0)
+
+	for range 20 {
+		ms.Update(1.0 / 60)
+	}
+	if want := (geom.Vec2I{X: -1, Y: 1}); bounce.Movement.DesiredDir != want {
+		t.Errorf("bounce DesiredDir %v after hitting the right wall, want X flipped %v", bounce.Movement.DesiredDir, want)
+	}
+	if bounce.Position.X >= 72 {
+		t.Errorf("bounce at X %v, want heading back left from the wall at 72", bounce.Position.X)
+	}
+	if stop.Movement.DesiredDir != (geom.Vec2I{}) {
+		t.Errorf("stop DesiredDir %v after hitting the wall, want cleared", stop.Movement.DesiredDir)
+	}
+	at := stop.Position.Vec2
+	ms.Update(1.0 / 60)
+	if stop.Position.Vec2 != at || stop.Movement.IsMoving {
+		t.Errorf("stop moved from %v to %v after hitting the wall, want stopped dead", at, stop.Position.Vec2)
+	}
+}
+
+func TestVelocityZeroedOnBlockedAxis(t *testing.T) {
+	ents := NewEntityManager()
+	// Resting against the right wall, sliding down it
+	e := mover(80-8-collision.Epsilon, 20, 60, geom.Vec2I{X: 1, Y: 1})
+	ents.Add(e)
+	ms := NewMovementSystem(ents, roomMap(t), 0)
+
+	ms.Update(1.0 / 60)
+	v := e.Movement.Velocity
+	if v.X != 0 {
+		t.Errorf("Velocity.X %v pushing into the wall, want 0", v.X)
+	}
+	if want := 60 / math.Sqrt2; !near(v.Y, want) {
+		t.Errorf("Velocity.Y %v sliding along the wall, want %v", v.Y, want)
+	}
+}
+
+func TestImageLayerRender(t *testing.T) {
+	all := make([]int, 12*4)
+	for i := range all {
+		all[i] = 1
+	}
+	load := func(t *testing.T, before int) *RenderSystem {
+		fx := testutil.MapFixture{
+			Width: 12, Height: 4, TileW: 16, TileH: 16, Columns: 2, Rows: 2,
+			Layers: [][]int{all, nil},
+			ImageLayers: []testutil.ImageLayer{{
+				Name: "sky", W: 32, H: 32, Before: before,
+				Attrs: `offsetx="8" offsety="4" parallaxx="0.5" parallaxy="0.5" opacity="0.25"`,
+			}},
+		}
+		tm, err := assetmgr.NewTileMapFromTmx(fx.FS(), testutil.MapPath, assetmgr.NewAssets())
+		if err != nil {
+			t.Fatal(err)
+		}
+		cam := camera.NewCamera(geom.Size{W: 96, H: 64}, image.Rect(0, 0, 192, 64))
+		// Centred on X 80 puts the camera at X 32
+		target := &Entity{Position: &PositionComponent{Vec2: geom.Vec2{X: 80, Y: 32}}}
+		rs := NewRenderSystem(NewEntityManager(), cam, target, tm)
+		rs.Update(1.0 / 60)
+		return rs
+	}
+
+	rs := load(t, 1)
+	layers := rs.tileMap.ImageLayers
+	if len(layers) != 1 {
+		t.Fatalf("loaded %d image layers, want 1", len(layers))
+	}
+	il := layers[0]
+	if il.Name != "sky" || il.Before != 1 || il.Offset != (geom.Vec2{X: 8, Y: 4}) ||
+		il.Parallax != (geom.Vec2{X: 0.5, Y: 0.5}) || il.Opacity != 0.25 {
+		t.Errorf("image layer %+v, want sky before layer 1 at (8, 4) with parallax 0.5 and opacity 0.25", il)
+	}
+	if b := il.Img.Bounds(); b.Dx() != 32 || b.Dy() != 32 {
+		t.Errorf("image is %dx%d, want 32x32", b.Dx(), b.Dy())
+	}
+
+	// Half parallax shifts it by half the camera's 32px, to screen X 8+16-32
+	screen := ebiten.NewImage(96, 64)
+	rs.DrawOrder = []DrawPass{{Kind: PassImageLayers, Layer: 1}}
+	rs.Draw(screen)
+	if x := rs.opts.GeoM.Element(0, 2); !near(x, -8) {
+		t.Errorf("image layer drawn at screen X %v, want -8", x)
+	}
+
+	// Z-order shows in the last draw: the image layer over the opaque tile
+	// layer 0 when authored after it, the tiles when authored before
+	for _, tt := range []struct {
+		before    int
+		wantAlpha float32
+	}{{1, 0.25}, {0, 1}} {
+		rs := load(t, tt.before)
+		rs.Draw(screen)
+		if got := rs.opts.ColorScale.A(); got != tt.wantAlpha {
+			t.Errorf("image layer before layer %d: last draw alpha %v, want %v", tt.before, got, tt.wantAlpha)
+		}
+	}
+}
+
+func TestMixedTileSizes(t *testing.T) {
+	// A 32px tree (global ID 8) in a map of 16px tiles, in the cell left of
+	// the camera's view
+	trees := make([]int, 8*4)
+	trees[3*8+1] = 8
+	fx := testutil.MapFixture{
+		Width: 8, Height: 4, TileW: 16, TileH: 16, Columns: 2, Rows: 2,
+		Layers: [][]int{trees},
+		Extra: []testutil.Tileset{
+			{Name: "trees", FirstGid: 7, TileW: 32, TileH: 32, Columns: 2, Rows: 1},
+		},
+	}
+	tm, err := assetmgr.NewTileMapFromTmx(fx.FS(), testutil.MapPath, assetmgr.NewAssets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tm.Tilesets().MaxTileSize(); got != (geom.Size{W: 32, H: 32}) {
+		t.Errorf("MaxTileSize = %v, want 32x32", got)
+	}
+	if cols, rows := tm.TileOverhang(); cols != 1 || rows != 1 {
+		t.Errorf("TileOverhang = %d, %d, want 1, 1", cols, rows)
+	}
+
+	// Tiles sit on the bottom left of their cell
+	tree, err := tm.GetImageById(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grass, err := tm.GetImageById(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tm.TileOrigin(1, 3, tree), (geom.Vec2{X: 16, Y: 32}); got != want {
+		t.Errorf("tree origin %v, want %v", got, want)
+	}
+	if got, want := tm.TileOrigin(1, 3, grass), (geom.Vec2{X: 16, Y: 48}); got != want {
+		t.Errorf("grass origin %v, want its own cell %v", got, want)
+	}
+
+	// With the camera at X 32 the tree's cell is out of view but the tree
+	// reaches into it
+	cam := camera.NewCamera(geom.Size{W: 96, H: 64}, image.Rect(0, 0, 128, 64))
+	target := &Entity{Position: &PositionComponent{Vec2: geom.Vec2{X: 80, Y: 32}}}
+	rs := NewRenderSystem(NewEntityManager(), cam, target, tm)
+	rs.Update(1.0 / 60)
+	rs.opts.GeoM.Translate(1000, 1000) // Left as is if the tree isn't drawn
+	rs.Draw(ebiten.NewImage(96, 64))
+	if x, y := rs.opts.GeoM.Element(0, 2), rs.opts.GeoM.Element(1, 2); !near(x, -16) || !near(y, 32) {
+		t.Errorf("tree drawn at screen (%v, %v), want (-16, 32)", x, y)
+	}
+
+	// A map of one tile size has no overhang
+	fx.Extra = nil
+	fx.Layers = nil
+	uniform, err := assetmgr.NewTileMapFromTmx(fx.FS(), testutil.MapPath, assetmgr.NewAssets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cols, rows := uniform.TileOverhang(); cols != 0 || rows != 0 {
+		t.Errorf("uniform map TileOverhang = %d, %d, want 0, 0", cols, rows)
+	}
+}
+
+func TestVisibleTileRectZoomed(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		extra []testutil.Tileset
+		want  image.Rectangle
+	}{
+		// Covering 40-72 by 20-36 is tiles 2-4 by 1-2, padded right and down
+		{"uniform tiles", nil, image.Rect(2, 1, 5, 3)},
+		// A 32px tileset reaches a cell further up and right
+		{"32px tileset", []testutil.Tileset{
+			{Name: "trees", FirstGid: 5, TileW: 32, TileH: 32, Columns: 2, Rows: 1},
+		}, image.Rect(1, 1, 5, 4)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fx := testutil.MapFixture{
+				Width: 20, Height: 10, TileW: 16, TileH: 16, Columns: 2, Rows: 2,
+				Extra: tt.extra,
+			}
+			tm, err := assetmgr.NewTileMapFromTmx(fx.FS(), testutil.MapPath, assetmgr.NewAssets())
+			if err != nil {
+				t.Fatal(err)
+			}
+			cam := camera.NewCameraAt(geom.Size{W: 64, H: 32}, image.Rect(0, 0, 320, 160), geom.Vec2{X: 56, Y: 28})
+			cam.SetZoom(2)
+			if cam.X != 40 || cam.Y != 20 {
+				t.Fatalf("camera at (%v, %v), want (40, 20)", cam.X, cam.Y)
+			}
+			rs := NewRenderSystem(NewEntityManager(), cam, &Entity{Position: &PositionComponent{}}, tm)
+
+			if got := rs.VisibleTileRect(); got != tt.want {
+				t.Errorf("VisibleTileRect = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
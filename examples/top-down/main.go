@@ -16,8 +16,11 @@ const (
 func main() {
 	ebiten.SetWindowSize(screenW, screenH)
 	ebiten.SetWindowTitle("Top down Example")
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 	scene := &ExampleScene{}
-	err := ebiten.RunGame(engine.NewGame(scene, geom.Size{W: screenW, H: screenH}))
+	game := engine.NewGame(scene, geom.Size{W: screenW, H: screenH})
+	game.SetLayoutMode(engine.LayoutIntegerScale)
+	err := ebiten.RunGame(game)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
 
@@ -65,6 +66,8 @@ type pScript struct {
 	curFrame   int
 	animations map[string][]*ebiten.Image
 	attacking  bool
+	fade       engine.Crossfade
+	blends     map[[2]string]float64 // Crossfade seconds between actions e.g. {"idle", "walk"}
 }
 
 func (ps *pScript) Update(e *engine.Entity, dt float64) {
@@ -81,6 +84,7 @@ func (ps *pScript) Update(e *engine.Entity, dt float64) {
 
 	// update Render.Img
 	e.Render.Img = ps.animations[ps.curAnim][ps.curFrame]
+	ps.fade.Apply(e.Render)
 }
 
 func (ps *pScript) updateAttackAnimations(m *engine.MovementComponent, dt float64) {
@@ -112,7 +116,10 @@ func (ps *pScript) updateWalkAnimations(m *engine.MovementComponent, dt float64)
 }
 
 func tickAnimationFrame(ps *pScript, nextAnim string, dt float64) {
+	ps.fade.Update(dt)
 	if nextAnim != ps.curAnim {
+		// Blend out of the current frame if this transition has a crossfade
+		ps.fade.Start(ps.animations[ps.curAnim][ps.curFrame], ps.blendTime(ps.curAnim, nextAnim))
 		ps.curFrame = 0
 		ps.curAnim = nextAnim
 	} else {
@@ -127,6 +134,14 @@ func tickAnimationFrame(ps *pScript, nextAnim string, dt float64) {
 	ps.curFrame %= len(ps.animations[ps.curAnim])
 }
 
+// blendTime returns the crossfade duration between two animations based on
+// their action (e.g. "idle" from "idle_down"). 0 means an instant cut
+func (ps *pScript) blendTime(from, to string) float64 {
+	fromAction, _, _ := strings.Cut(from, "_")
+	toAction, _, _ := strings.Cut(to, "_")
+	return ps.blends[[2]string{fromAction, toAction}]
+}
+
 func getDir() geom.Vec2I {
 	direction := geom.Vec2I{}
 	if ebiten.IsKeyPressed(ebiten.KeyUp) {
@@ -145,48 +160,34 @@ func getDir() geom.Vec2I {
 }
 
 func getDirString(m *engine.MovementComponent) string {
-	switch {
-	case m.FacingDir.Y < 0:
-		return "up"
-	case m.FacingDir.Y > 0:
-		return "down"
-	case m.FacingDir.X < 0:
-		return "left"
-	case m.FacingDir.X > 0:
-		return "right"
-	default:
-		return "down"
-	}
+	return m.Cardinal(engine.FacingYPriority)
 }
 
 func newPScript(assets *assetmgr.Assets) *pScript {
-	a := map[string][]*ebiten.Image{}
-
 	// Setup animations
-	anims, err := assets.GetSpriteSheet("Player")
+	sprites, err := assets.GetSpriteSheet("Player")
 	if err != nil {
 		panic("Error retrieving spritesheet 'Player'")
 	}
 
-	a["idle_down"] = anims[0:6]
-	a["idle_up"] = anims[6:12]
-	a["idle_right"] = anims[12:18]
-	a["idle_left"] = anims[18:24]
-
-	a["walk_down"] = anims[24:30]
-	a["walk_up"] = anims[30:36]
-	a["walk_right"] = anims[36:42]
-	a["walk_left"] = anims[42:48]
-
-	a["attack_down"] = anims[96:102]
-	a["attack_up"] = anims[102:108]
-	a["attack_right"] = anims[108:114]
-	a["attack_left"] = anims[114:120]
+	// Each action is 4 rows of 6 frames (down, up, right, left)
+	a, err := assetmgr.DirectionalAnimations(sprites, 6, map[string]int{
+		"idle":   0,
+		"walk":   4,
+		"attack": 16,
+	})
+	if err != nil {
+		panic(fmt.Errorf("Unable to setup player animations %w", err))
+	}
 
 	return &pScript{
 		animRate:   0.15,
 		curFrame:   0,
 		curAnim:    "idle_down",
 		animations: a,
+		blends: map[[2]string]float64{
+			{"idle", "walk"}: 0.1,
+			{"walk", "idle"}: 0.1,
+		},
 	}
 }
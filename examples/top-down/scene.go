@@ -9,6 +9,7 @@ import (
 	"github.com/samredway/ebx/camera"
 	"github.com/samredway/ebx/engine"
 	gameassets "github.com/samredway/ebx/examples/top-down/assets"
+	"github.com/samredway/ebx/geom"
 )
 
 // ExampleScene demonstrates using the topdown.BaseScene for rapid prototyping
@@ -16,6 +17,7 @@ type ExampleScene struct {
 	engine.BaseScene
 	assets    *assetmgr.Assets
 	tilemap   *assetmgr.TileMap
+	cam       *camera.Camera
 	entities  *engine.EntityManager
 	renderSys *engine.RenderSystem
 	moveSys   *engine.MovementSystem
@@ -25,6 +27,7 @@ type ExampleScene struct {
 func (es *ExampleScene) OnEnter() {
 	// Load tilemap -----------------------------------------------------------
 	es.assets = assetmgr.NewAssets()
+	es.Track(es.assets)
 	es.assets.LoadTileSetFromFS(gameassets.GameFS, "Dungeon_floor", "DungeonFloors.png", 16, 16)
 	var err error
 	es.tilemap, err = assetmgr.NewTileMapFromTmx(gameassets.GameFS, "example.tmx", es.assets)
@@ -43,17 +46,25 @@ func (es *ExampleScene) OnEnter() {
 	mapWidth := es.tilemap.MapWidth * es.tilemap.TileWidth
 	mapHeight := es.tilemap.MapHeight * es.tilemap.TileHeight
 	bounds := image.Rect(0, 0, mapWidth, mapHeight)
-	cam := camera.NewCamera(es.Viewport, bounds)
-	cam.Zoom = 2.0
-	es.renderSys = engine.NewRenderSystem(es.entities, cam, player, es.tilemap)
+	es.cam = camera.NewCamera(es.Viewport, bounds)
+	es.cam.Zoom = 2.0
+	es.cam.SetRegions(es.tilemap.CameraRegions())
+	es.renderSys = engine.NewRenderSystem(es.entities, es.cam, player, es.tilemap)
 	es.moveSys = engine.NewMovementSystem(es.entities, es.tilemap, 1)
+
+	// Updated in phase order by the BaseScene's Update
+	es.Systems.Add(engine.PhaseScripts, es.entities)
+	es.Systems.Add(engine.PhaseMovement, es.moveSys)
+	es.Systems.Add(engine.PhaseRender, es.renderSys)
+	es.Systems.Add(engine.PhaseCleanup, engine.SystemFunc(func(float64) { es.entities.RemoveDead() }))
 }
 
-func (es *ExampleScene) Update(dt float64) (engine.Scene, error) {
-	es.entities.Update(dt)
-	es.moveSys.Update(dt)
-	es.entities.RemoveDead()
-	return nil, nil
+// SetViewport keeps the camera in sync when the window is resized
+func (es *ExampleScene) SetViewport(view geom.Size) {
+	es.BaseScene.SetViewport(view)
+	if es.cam != nil {
+		es.cam.SetViewport(view)
+	}
 }
 
 func (es *ExampleScene) Draw(screen *ebiten.Image) {
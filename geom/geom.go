@@ -4,11 +4,17 @@ import "math"
 
 type Vec2 struct{ X, Y float64 }
 
+// Finite reports whether both components of vec are neither NaN nor infinite
+func Finite(vec Vec2) bool {
+	return !math.IsNaN(vec.X) && !math.IsNaN(vec.Y) && !math.IsInf(vec.X, 0) && !math.IsInf(vec.Y, 0)
+}
+
 // Normalize returns a unit-length vector pointing in the same direction as vec.
-// If vec has zero length, it returns the zero vector unchanged.
+// If vec has zero length, it returns the zero vector unchanged. Vectors that
+// are not finite (see Finite) also give the zero vector rather than NaN
 func Normalize(vec Vec2) Vec2 {
 	length := math.Hypot(vec.X, vec.Y)
-	if length == 0 {
+	if length == 0 || !Finite(vec) {
 		return Vec2{}
 	}
 	return Vec2{
@@ -20,3 +26,24 @@ func Normalize(vec Vec2) Vec2 {
 type Vec2I struct{ X, Y int }
 
 type Size struct{ W, H int }
+
+// Rect is an axis aligned rectangle, X, Y being the top left corner
+type Rect struct{ X, Y, W, H float64 }
+
+// Overlaps reports whether r and o intersect. Rects that only touch along an
+// edge do not overlap, and neither do rects with no area
+func (r Rect) Overlaps(o Rect) bool {
+	return r.W > 0 && r.H > 0 && o.W > 0 && o.H > 0 &&
+		r.X < o.X+o.W && o.X < r.X+r.W &&
+		r.Y < o.Y+o.H && o.Y < r.Y+r.H
+}
+
+// Contains reports whether p is inside r, including its top and left edges
+func (r Rect) Contains(p Vec2) bool {
+	return p.X >= r.X && p.X < r.X+r.W && p.Y >= r.Y && p.Y < r.Y+r.H
+}
+
+// Centre returns the centre point of the rect
+func (r Rect) Centre() Vec2 {
+	return Vec2{X: r.X + r.W/2, Y: r.Y + r.H/2}
+}
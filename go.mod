@@ -5,13 +5,14 @@ go 1.24.0
 toolchain go1.24.8
 
 require (
-	github.com/samredway/ebitmx v0.0.0-20251018154639-fb871632bd27
 	github.com/hajimehoshi/ebiten/v2 v2.9.2
+	github.com/samredway/ebitmx v0.0.0-20251018154639-fb871632bd27
 )
 
 require (
 	github.com/ebitengine/gomobile v0.0.0-20250923094054-ea854a63cce1 // indirect
 	github.com/ebitengine/hideconsole v1.0.0 // indirect
+	github.com/ebitengine/oto/v3 v3.4.0 // indirect
 	github.com/ebitengine/purego v0.10.0-alpha.2 // indirect
 	github.com/jezek/xgb v1.1.1 // indirect
 	golang.org/x/sync v0.17.0 // indirect
@@ -0,0 +1,190 @@
+// Package testutil builds in-memory asset fixtures for tests, so loading,
+// sheet splitting and tilemap parsing can be tested without files on disk
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing/fstest"
+)
+
+// Paths of the files in a MapFixture's FS
+const (
+	MapPath          = "map.tmx"
+	TilesetPath      = "tiles.tsx"
+	TilesetImagePath = "tiles.png"
+)
+
+// PNG encodes a w x h image split into frameW x frameH frames, each a different
+// solid colour, so frames cut from it can be told apart. The size is exactly
+// w x h even when it isn't a whole number of frames
+func PNG(w, h, frameW, frameH int) []byte {
+	frameW, frameH = max(frameW, 1), max(frameH, 1)
+	cols := (w + frameW - 1) / frameW
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, FrameColour(x/frameW+y/frameH*cols))
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		panic(fmt.Sprintf("failed to encode fixture PNG: %v", err))
+	}
+	return buf.Bytes()
+}
+
+// FrameColour returns the colour PNG fills frame i with
+func FrameColour(i int) color.NRGBA {
+	return color.NRGBA{R: uint8(i * 37), G: uint8(i * 91), B: uint8(i * 173), A: 0xff}
+}
+
+// MapFixture describes a generated orthogonal tilemap with one tileset at
+// firstgid 1, plus any Extra tilesets
+type MapFixture struct {
+	Width, Height int // Map size in tiles
+	TileW, TileH  int // Tile size in px
+	Columns, Rows int // Tileset size in tiles
+
+	// Layers holds the global tile IDs of each tile layer, row by row. Missing
+	// or short layers are padded with empty tiles. No layers gives one empty
+	// layer
+	Layers [][]int
+
+	// LayerAttrs holds extra XML attributes for each tile layer by index, e.g.
+	// `visible="0"` or `opacity="0.5"`
+	LayerAttrs []string
+
+	Embedded bool // Embed the tileset in the TMX rather than a .tsx file
+
+	Extra []Tileset // More tilesets, each in its own .tsx file
+
+	ImageLayers []ImageLayer
+}
+
+// ImageLayer describes an image layer of a MapFixture, with a W x H px image
+// at Name.png
+type ImageLayer struct {
+	Name   string
+	W, H   int
+	Before int    // Index of the tile layer it is written before, >= the number of layers = after them all
+	Attrs  string // Extra XML attributes, e.g. `offsetx="8"` or `parallaxx="0.5"`
+}
+
+// Tileset describes an extra tileset of a MapFixture, defined in Name.tsx with
+// its image at Name.png
+type Tileset struct {
+	Name          string
+	FirstGid      int
+	TileW, TileH  int // Tile size in px
+	Columns, Rows int // Tileset size in tiles
+}
+
+// FS returns the map at MapPath, its tileset (at TilesetPath unless embedded)
+// and the tileset image at TilesetImagePath, which is exactly
+// Columns*TileW x Rows*TileH px, along with the files of the Extra tilesets
+// and the images of the ImageLayers
+func (f MapFixture) FS() fstest.MapFS {
+	first := f.first()
+	fsys := fstest.MapFS{MapPath: {Data: []byte(f.tmx())}}
+	for i, ts := range append([]Tileset{first}, f.Extra...) {
+		fsys[ts.imagePath()] = &fstest.MapFile{Data: PNG(
+			ts.Columns*ts.TileW, ts.Rows*ts.TileH, ts.TileW, ts.TileH,
+		)}
+		if i == 0 && f.Embedded {
+			continue
+		}
+		fsys[ts.path()] = &fstest.MapFile{
+			Data: []byte(`<?xml version="1.0" encoding="UTF-8"?>` + "\n" + ts.element("") + "\n"),
+		}
+	}
+	for _, il := range f.ImageLayers {
+		fsys[il.Name+".png"] = &fstest.MapFile{Data: PNG(il.W, il.H, il.W, il.H)}
+	}
+	return fsys
+}
+
+// first returns the map's own tileset, at TilesetPath
+func (f MapFixture) first() Tileset {
+	return Tileset{
+		Name: strings.TrimSuffix(TilesetPath, ".tsx"), FirstGid: 1,
+		TileW: f.TileW, TileH: f.TileH, Columns: f.Columns, Rows: f.Rows,
+	}
+}
+
+func (ts Tileset) path() string      { return ts.Name + ".tsx" }
+func (ts Tileset) imagePath() string { return ts.Name + ".png" }
+
+// element returns the tileset element, with attrs added for embedding
+func (ts Tileset) element(attrs string) string {
+	return fmt.Sprintf(
+		`<tileset%s name="%s" tilewidth="%d" tileheight="%d" tilecount="%d" columns="%d">
+ <image source="%s" width="%d" height="%d"/>
+</tileset>`,
+		attrs, ts.Name, ts.TileW, ts.TileH, ts.Columns*ts.Rows, ts.Columns,
+		ts.imagePath(), ts.Columns*ts.TileW, ts.Rows*ts.TileH,
+	)
+}
+
+func (f MapFixture) tmx() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" renderorder="right-down" width="%d" height="%d" tilewidth="%d" tileheight="%d" infinite="0">
+`, f.Width, f.Height, f.TileW, f.TileH)
+	if f.Embedded {
+		b.WriteString(f.first().element(` firstgid="1"`) + "\n")
+	} else {
+		fmt.Fprintf(&b, `<tileset firstgid="1" source="%s"/>`+"\n", TilesetPath)
+	}
+	for _, ts := range f.Extra {
+		fmt.Fprintf(&b, `<tileset firstgid="%d" source="%s"/>`+"\n", ts.FirstGid, ts.path())
+	}
+
+	layers := f.Layers
+	if len(layers) == 0 {
+		layers = [][]int{nil}
+	}
+	for i, data := range layers {
+		f.writeImageLayers(&b, func(before int) bool { return before == i })
+		cells := make([]string, f.Width*f.Height)
+		for j := range cells {
+			id := 0
+			if j < len(data) {
+				id = data[j]
+			}
+			cells[j] = fmt.Sprint(id)
+		}
+		attrs := ""
+		if i < len(f.LayerAttrs) {
+			attrs = " " + f.LayerAttrs[i]
+		}
+		fmt.Fprintf(&b, `<layer id="%d" name="Layer %d" width="%d" height="%d"%s>
+<data encoding="csv">%s</data>
+</layer>
+`, i+1, i+1, f.Width, f.Height, attrs, strings.Join(cells, ","))
+	}
+	f.writeImageLayers(&b, func(before int) bool { return before >= len(layers) })
+	b.WriteString("</map>\n")
+	return b.String()
+}
+
+// writeImageLayers writes the image layers whose Before matches
+func (f MapFixture) writeImageLayers(b *strings.Builder, match func(before int) bool) {
+	for j, il := range f.ImageLayers {
+		if !match(il.Before) {
+			continue
+		}
+		attrs := ""
+		if il.Attrs != "" {
+			attrs = " " + il.Attrs
+		}
+		fmt.Fprintf(b, `<imagelayer id="%d" name="%s"%s>
+ <image source="%s.png" width="%d" height="%d"/>
+</imagelayer>
+`, 100+j, il.Name, attrs, il.Name, il.W, il.H)
+	}
+}
@@ -0,0 +1,173 @@
+// Package sound plays background music and sound effects through Ebiten's
+// audio package. It is kept out of engine so games without sound don't link
+// an audio driver
+package sound
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+// musicPlayer is the part of an audio.Player the Music uses
+type musicPlayer interface {
+	Play()
+	Pause()
+	SetVolume(volume float64)
+	Close() error
+}
+
+// Music plays one looping background track at a time and can crossfade to
+// another, e.g. when a boss fight starts. Tracks loop gaplessly. A track that
+// has faded out is closed, so players are not leaked. Call Update once per
+// frame. Music is an engine.Deallocator so a scene can Track it to close every
+// player when it exits. Create it with NewMusic, the zero value has no audio
+// context so Play returns an error, and its zero Volume would be silent
+//
+// Example:
+//
+//	stream, err := vorbis.DecodeWithSampleRate(ctx.SampleRate(), f)
+//	...
+//	s.music.Play(stream, stream.Length(), 2)
+type Music struct {
+	Volume float64 // Master volume 0-1 applied on top of the fades
+
+	current *musicTrack   // The track playing or fading in, nil when stopped
+	fading  []*musicTrack // Previous tracks fading out
+	paused  bool
+
+	newPlayer func(stream io.ReadSeeker, length int64) (musicPlayer, error)
+}
+
+// musicTrack is a player and its fade envelope
+type musicTrack struct {
+	player musicPlayer
+	level  float64 // Current fade level 0-1
+	rate   float64 // Change in level per second, negative when fading out
+}
+
+// Play starts looping stream and crossfades to it over fade seconds: the new
+// track ramps up from silence while the current one ramps down and is closed.
+// stream is 16 bit stereo PCM at the audio context's sample rate (as the
+// decoders in ebiten's audio packages give) and length is its size in bytes.
+// A fade of 0 cuts straight to the new track
+func (m *Music) Play(stream io.ReadSeeker, length int64, fade float64) error {
+	if m.newPlayer == nil {
+		return fmt.Errorf("failed to create music player: no audio context, create the Music with NewMusic")
+	}
+	p, err := m.newPlayer(stream, length)
+	if err != nil {
+		return fmt.Errorf("failed to create music player: %w", err)
+	}
+
+	m.fadeOut(fade)
+	m.current = &musicTrack{player: p}
+	if fade > 0 {
+		m.current.rate = 1 / fade
+	} else {
+		m.current.level = 1
+	}
+	m.apply(m.current)
+	if !m.paused {
+		p.Play()
+	}
+	return nil
+}
+
+// Stop fades the current track out over fade seconds and then closes it. A
+// fade of 0 stops it straight away
+func (m *Music) Stop(fade float64) {
+	m.fadeOut(fade)
+	m.current = nil
+}
+
+// Pause pauses every track, keeping their position and fade
+func (m *Music) Pause() {
+	m.paused = true
+	m.each(func(t *musicTrack) { t.player.Pause() })
+}
+
+// Resume carries on playing after Pause
+func (m *Music) Resume() {
+	m.paused = false
+	m.each(func(t *musicTrack) { t.player.Play() })
+}
+
+// Paused reports whether the music is paused
+func (m *Music) Paused() bool { return m.paused }
+
+// Playing reports whether there is a current track, i.e. Play has been called
+// and not followed by Stop. It is still true while paused
+func (m *Music) Playing() bool { return m.current != nil }
+
+// Update advances the fades by dt seconds and closes tracks that have faded
+// out. Fades don't advance while paused
+func (m *Music) Update(dt float64) {
+	if m.paused {
+		return
+	}
+	if t := m.current; t != nil {
+		t.level = min(1, t.level+t.rate*dt)
+		m.apply(t)
+	}
+
+	kept := m.fading[:0]
+	for _, t := range m.fading {
+		t.level = max(0, t.level+t.rate*dt)
+		if t.level <= 0 {
+			t.player.Close()
+			continue
+		}
+		m.apply(t)
+		kept = append(kept, t)
+	}
+	clear(m.fading[len(kept):])
+	m.fading = kept
+}
+
+// Deallocate closes every player straight away
+func (m *Music) Deallocate() {
+	m.each(func(t *musicTrack) { t.player.Close() })
+	m.current = nil
+	m.fading = nil
+}
+
+// fadeOut moves the current track, if any, to the fading tracks, ramping down
+// from its level over fade seconds
+func (m *Music) fadeOut(fade float64) {
+	t := m.current
+	if t == nil {
+		return
+	}
+	if fade <= 0 {
+		t.player.Close()
+		return
+	}
+	t.rate = -1 / fade
+	m.fading = append(m.fading, t)
+}
+
+// apply sets a track's player volume from its fade level and the master volume
+func (m *Music) apply(t *musicTrack) {
+	t.player.SetVolume(t.level * m.Volume)
+}
+
+func (m *Music) each(fn func(t *musicTrack)) {
+	if m.current != nil {
+		fn(m.current)
+	}
+	for _, t := range m.fading {
+		fn(t)
+	}
+}
+
+// NewMusic is constructor for Music
+func NewMusic(ctx *audio.Context) *Music {
+	return &Music{
+		Volume: 1,
+		newPlayer: func(stream io.ReadSeeker, length int64) (musicPlayer, error) {
+			return ctx.NewPlayer(audio.NewInfiniteLoop(stream, length))
+		},
+	}
+}
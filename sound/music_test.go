@@ -0,0 +1,151 @@
+package sound
+
+import (
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+// stubPlayer records what Music does to it
+type stubPlayer struct {
+	volume  float64
+	playing bool
+	closed  bool
+}
+
+func (p *stubPlayer) Play()                    { p.playing = true }
+func (p *stubPlayer) Pause()                   { p.playing = false }
+func (p *stubPlayer) SetVolume(volume float64) { p.volume = volume }
+func (p *stubPlayer) Close() error             { p.closed = true; return nil }
+
+// stubMusic returns a Music making stub players, and the players it made
+func stubMusic() (*Music, *[]*stubPlayer) {
+	var players []*stubPlayer
+	m := &Music{
+		Volume: 1,
+		newPlayer: func(io.ReadSeeker, int64) (musicPlayer, error) {
+			p := &stubPlayer{}
+			players = append(players, p)
+			return p, nil
+		},
+	}
+	return m, &players
+}
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestMusicCrossfadeEnvelope(t *testing.T) {
+	m, players := stubMusic()
+	if err := m.Play(strings.NewReader(""), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	a := (*players)[0]
+	if !a.playing || a.volume != 1 {
+		t.Fatalf("track A playing %v at %v, want cut straight in at 1", a.playing, a.volume)
+	}
+
+	// Crossfade to B over 2s
+	if err := m.Play(strings.NewReader(""), 0, 2); err != nil {
+		t.Fatal(err)
+	}
+	b := (*players)[1]
+	if !b.playing || b.volume != 0 {
+		t.Fatalf("track B playing %v at %v, want starting silent", b.playing, b.volume)
+	}
+	for i := 1; i <= 7; i++ {
+		m.Update(0.25)
+		want := float64(i) * 0.125
+		if !near(b.volume, want) || !near(a.volume, 1-want) {
+			t.Errorf("%.2fs in: A at %v, B at %v, want %v and %v", float64(i)*0.25, a.volume, b.volume, 1-want, want)
+		}
+		if a.closed {
+			t.Fatalf("A closed %.2fs into a 2s fade", float64(i)*0.25)
+		}
+	}
+
+	m.Update(0.25)
+	if !a.closed || len(m.fading) != 0 {
+		t.Errorf("A closed %v with %d tracks fading after the fade, want closed and none", a.closed, len(m.fading))
+	}
+	if b.volume != 1 || b.closed {
+		t.Errorf("B at %v closed %v after the fade, want playing at 1", b.volume, b.closed)
+	}
+
+	// Fade levels stay clamped
+	m.Update(1)
+	if b.volume != 1 {
+		t.Errorf("B at %v after fading in, want 1", b.volume)
+	}
+}
+
+func TestMusicMasterVolume(t *testing.T) {
+	m, players := stubMusic()
+	m.Volume = 0.5
+	m.Play(strings.NewReader(""), 0, 1)
+	p := (*players)[0]
+
+	m.Update(0.5)
+	if !near(p.volume, 0.25) {
+		t.Errorf("half way through the fade at master 0.5 volume is %v, want 0.25", p.volume)
+	}
+}
+
+func TestMusicPauseHoldsFade(t *testing.T) {
+	m, players := stubMusic()
+	m.Play(strings.NewReader(""), 0, 0)
+	m.Play(strings.NewReader(""), 0, 1)
+	a, b := (*players)[0], (*players)[1]
+	m.Update(0.5)
+
+	m.Pause()
+	if a.playing || b.playing {
+		t.Error("tracks still playing after Pause")
+	}
+	m.Update(10)
+	if !near(a.volume, 0.5) || !near(b.volume, 0.5) || a.closed {
+		t.Errorf("fade moved while paused: A at %v closed %v, B at %v", a.volume, a.closed, b.volume)
+	}
+
+	m.Resume()
+	if !a.playing || !b.playing {
+		t.Error("tracks not playing after Resume")
+	}
+	m.Update(0.5)
+	if !a.closed || b.volume != 1 {
+		t.Errorf("after the fade resumed A closed %v, B at %v, want closed and 1", a.closed, b.volume)
+	}
+}
+
+func TestMusicClosesPlayers(t *testing.T) {
+	m, players := stubMusic()
+	m.Play(strings.NewReader(""), 0, 0)
+	m.Stop(0)
+	if !(*players)[0].closed || m.Playing() {
+		t.Error("Stop(0) didn't close the track")
+	}
+
+	m.Play(strings.NewReader(""), 0, 0)
+	m.Play(strings.NewReader(""), 0, 1)
+	m.Deallocate()
+	for i, p := range *players {
+		if !p.closed {
+			t.Errorf("player %d not closed by Deallocate", i)
+		}
+	}
+}
+
+func TestMusicZeroValue(t *testing.T) {
+	var m Music
+	if err := m.Play(strings.NewReader(""), 0, 1); err == nil {
+		t.Error("Play without an audio context didn't error")
+	}
+	if m.Playing() {
+		t.Error("Playing after a failed Play")
+	}
+	m.Update(1.0 / 60)
+	m.Pause()
+	m.Resume()
+	m.Stop(1)
+	m.Deallocate()
+}
@@ -0,0 +1,136 @@
+package sound
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/samredway/ebx/camera"
+	"github.com/samredway/ebx/geom"
+)
+
+// soundPlayer is the part of an audio.Player the SoundSystem uses
+type soundPlayer interface {
+	Play()
+	IsPlaying() bool
+	SetVolume(volume float64)
+	Close() error
+}
+
+// SoundSystem plays one-shot sound effects by name. PlayAt makes a sound
+// quieter the further it is from the listener, the centre of the camera, so
+// it follows the camera as it moves. Update closes players that have finished,
+// so call it once per frame. SoundSystem is an engine.Deallocator so a scene
+// can Track it to close every player when it exits. The zero value can Add
+// sounds, but Play and PlayAt return an error without the audio context given
+// to NewSoundSystem, and it has no listener so PlayAt doesn't fall off. A zero
+// Volume is silent, NewSoundSystem starts it at 1
+//
+// Example:
+//
+//	stream, err := wav.DecodeWithSampleRate(ctx.SampleRate(), f)
+//	...
+//	pcm, err := io.ReadAll(stream)
+//	...
+//	s.sounds.Add("growl", pcm)
+//	s.sounds.PlayAt("growl", enemy.Position.Vec2)
+type SoundSystem struct {
+	Volume float64 // Master volume 0-1
+	Radius float64 // World px from the listener at which PlayAt sounds fade to silence, <= 0 = no falloff
+
+	camera  *camera.Camera
+	sounds  map[string][]byte
+	playing []soundPlayer
+
+	newPlayer func(pcm []byte) soundPlayer
+}
+
+// Add registers a sound under name. pcm is 16 bit stereo PCM at the audio
+// context's sample rate, e.g. read from one of ebiten's audio decoders
+func (ss *SoundSystem) Add(name string, pcm []byte) {
+	if ss.sounds == nil {
+		ss.sounds = map[string][]byte{}
+	}
+	ss.sounds[name] = pcm
+}
+
+// Play plays the sound added as name at full volume
+func (ss *SoundSystem) Play(name string) error {
+	return ss.play(name, 1)
+}
+
+// PlayAt plays the sound added as name as if it came from worldPos, at the
+// volume given by Attenuation. Sounds at or beyond Radius are skipped
+func (ss *SoundSystem) PlayAt(name string, worldPos geom.Vec2) error {
+	if _, ok := ss.sounds[name]; !ok {
+		return fmt.Errorf("no sound with name %s", name)
+	}
+	level := ss.Attenuation(worldPos)
+	if level <= 0 {
+		return nil
+	}
+	return ss.play(name, level)
+}
+
+// Attenuation returns the volume 0-1 for a sound at worldPos, before the
+// master Volume. It falls linearly from 1 at the listener to 0 at Radius, and
+// is always 1 without a listener camera
+func (ss *SoundSystem) Attenuation(worldPos geom.Vec2) float64 {
+	if ss.Radius <= 0 || ss.camera == nil {
+		return 1
+	}
+	listener := ss.camera.Centre()
+	dist := math.Hypot(worldPos.X-listener.X, worldPos.Y-listener.Y)
+	return max(0, 1-dist/ss.Radius)
+}
+
+// Update closes the players of sounds that have finished
+func (ss *SoundSystem) Update(dt float64) {
+	kept := ss.playing[:0]
+	for _, p := range ss.playing {
+		if p.IsPlaying() {
+			kept = append(kept, p)
+			continue
+		}
+		p.Close()
+	}
+	clear(ss.playing[len(kept):])
+	ss.playing = kept
+}
+
+// Deallocate closes every player straight away
+func (ss *SoundSystem) Deallocate() {
+	for _, p := range ss.playing {
+		p.Close()
+	}
+	ss.playing = nil
+}
+
+func (ss *SoundSystem) play(name string, level float64) error {
+	pcm, ok := ss.sounds[name]
+	if !ok {
+		return fmt.Errorf("no sound with name %s", name)
+	}
+	if ss.newPlayer == nil {
+		return fmt.Errorf("failed to play %s: no audio context, create the SoundSystem with NewSoundSystem", name)
+	}
+	p := ss.newPlayer(pcm)
+	p.SetVolume(level * ss.Volume)
+	p.Play()
+	ss.playing = append(ss.playing, p)
+	return nil
+}
+
+// NewSoundSystem is constructor for SoundSystem. cam is the listener and
+// radius how far away, in world px, PlayAt sounds can be heard
+func NewSoundSystem(ctx *audio.Context, cam *camera.Camera, radius float64) *SoundSystem {
+	return &SoundSystem{
+		Volume: 1,
+		Radius: radius,
+		camera: cam,
+		sounds: map[string][]byte{},
+		newPlayer: func(pcm []byte) soundPlayer {
+			return ctx.NewPlayerFromBytes(pcm)
+		},
+	}
+}
@@ -0,0 +1,129 @@
+package sound
+
+import (
+	"image"
+	"testing"
+
+	"github.com/samredway/ebx/camera"
+	"github.com/samredway/ebx/geom"
+)
+
+// stubEffect is a soundPlayer that plays until it is told it has finished
+type stubEffect struct {
+	stubPlayer
+	finished bool
+}
+
+func (p *stubEffect) IsPlaying() bool { return p.playing && !p.finished }
+
+// stubSounds returns a SoundSystem making stub players with the listener at
+// (100, 100), and the players it made
+func stubSounds(radius float64) (*SoundSystem, *camera.Camera, *[]*stubEffect) {
+	var players []*stubEffect
+	cam := camera.NewCameraAt(geom.Size{W: 100, H: 100}, image.Rect(0, 0, 1000, 1000), geom.Vec2{X: 100, Y: 100})
+	ss := &SoundSystem{
+		Volume: 1,
+		Radius: radius,
+		camera: cam,
+		sounds: map[string][]byte{"growl": nil},
+		newPlayer: func([]byte) soundPlayer {
+			p := &stubEffect{}
+			players = append(players, p)
+			return p
+		},
+	}
+	return ss, cam, &players
+}
+
+func TestPlayAtVolumeFallsWithDistance(t *testing.T) {
+	ss, _, players := stubSounds(200)
+
+	prev := 2.0
+	for _, dist := range []float64{0, 10, 50, 100, 150, 199} {
+		if err := ss.PlayAt("growl", geom.Vec2{X: 100 + dist, Y: 100}); err != nil {
+			t.Fatal(err)
+		}
+		p := (*players)[len(*players)-1]
+		if p.volume >= prev {
+			t.Errorf("volume %v at %v px, not quieter than %v closer in", p.volume, dist, prev)
+		}
+		if dist == 0 && p.volume != 1 {
+			t.Errorf("volume %v at the listener, want 1", p.volume)
+		}
+		prev = p.volume
+	}
+
+	// Distance is the same in every direction
+	ss.PlayAt("growl", geom.Vec2{X: 100, Y: 50})
+	ss.PlayAt("growl", geom.Vec2{X: 130, Y: 140})
+	if a, b := (*players)[len(*players)-2].volume, (*players)[len(*players)-1].volume; a != b {
+		t.Errorf("volumes %v and %v at the same distance", a, b)
+	}
+}
+
+func TestPlayAtBeyondRadiusSkipped(t *testing.T) {
+	ss, _, players := stubSounds(200)
+	for _, pos := range []geom.Vec2{{X: 300, Y: 100}, {X: 100, Y: 900}} {
+		if err := ss.PlayAt("growl", pos); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*players) != 0 {
+		t.Errorf("%d players made for sounds out of range, want none", len(*players))
+	}
+
+	if err := ss.PlayAt("roar", geom.Vec2{X: 900, Y: 900}); err == nil {
+		t.Error("PlayAt of an unknown sound out of range didn't error")
+	}
+}
+
+func TestPlayAtListenerFollowsCamera(t *testing.T) {
+	ss, cam, _ := stubSounds(200)
+	pos := geom.Vec2{X: 400, Y: 100}
+	if got := ss.Attenuation(pos); got != 0 {
+		t.Errorf("attenuation %v 300px away, want 0", got)
+	}
+	cam.CentreOn(geom.Vec2{X: 350, Y: 100})
+	if got := ss.Attenuation(pos); got != 0.75 {
+		t.Errorf("attenuation %v after the camera moved 50px away, want 0.75", got)
+	}
+}
+
+func TestSoundSystemClosesFinishedPlayers(t *testing.T) {
+	ss, _, players := stubSounds(0)
+	ss.Volume = 0.5
+	ss.Play("growl")
+	ss.Play("growl")
+	a, b := (*players)[0], (*players)[1]
+	if !a.playing || a.volume != 0.5 {
+		t.Errorf("player playing %v at %v, want playing at the master volume 0.5", a.playing, a.volume)
+	}
+
+	a.finished = true
+	ss.Update(1.0 / 60)
+	if !a.closed || b.closed || len(ss.playing) != 1 {
+		t.Errorf("after A finished A closed %v, B closed %v, %d playing", a.closed, b.closed, len(ss.playing))
+	}
+
+	ss.Deallocate()
+	if !b.closed {
+		t.Error("Deallocate didn't close the playing sound")
+	}
+}
+
+func TestSoundSystemZeroValue(t *testing.T) {
+	var ss SoundSystem
+	ss.Radius = 200
+	ss.Add("growl", nil)
+	if got := ss.Attenuation(geom.Vec2{X: 1000}); got != 1 {
+		t.Errorf("Attenuation without a camera = %v, want 1", got)
+	}
+	if err := ss.Play("growl"); err == nil {
+		t.Error("Play without an audio context didn't error")
+	}
+	if err := ss.PlayAt("growl", geom.Vec2{}); err == nil {
+		t.Error("PlayAt without an audio context didn't error")
+	}
+	ss.Update(1.0 / 60)
+	ss.Deallocate()
+}
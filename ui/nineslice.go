@@ -0,0 +1,70 @@
+// Package ui provides helpers for drawing interface elements such as menus
+// and dialog boxes on top of a scene. Everything here works in screen space
+package ui
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Insets are the widths px of the border on each side of a nine-slice image
+type Insets struct {
+	Left, Top, Right, Bottom int
+}
+
+// DrawNineSlice draws img stretched over dest on screen as a nine-slice panel:
+// the corners (sized by insets) are drawn unscaled, the edges stretched along
+// their length and the centre stretched to fill the rest. If dest is too small
+// for the corners they are shrunk to fit
+func DrawNineSlice(screen, img *ebiten.Image, insets Insets, dest image.Rectangle) {
+	srcs, dsts := nineSliceRects(img.Bounds(), insets, dest)
+
+	opts := &ebiten.DrawImageOptions{}
+	for i := range srcs {
+		src, dst := srcs[i], dsts[i]
+		if src.Empty() || dst.Empty() {
+			continue
+		}
+		opts.GeoM.Reset()
+		opts.GeoM.Scale(
+			float64(dst.Dx())/float64(src.Dx()),
+			float64(dst.Dy())/float64(src.Dy()),
+		)
+		opts.GeoM.Translate(float64(dst.Min.X), float64(dst.Min.Y))
+		screen.DrawImage(img.SubImage(src).(*ebiten.Image), opts)
+	}
+}
+
+// nineSliceRects splits src and dest into the 9 matching cells, row by row
+// from the top left. Cells share their whole pixel edges so any dest size is
+// covered without gaps or overlaps at the seams
+func nineSliceRects(src image.Rectangle, insets Insets, dest image.Rectangle) (srcs, dsts [9]image.Rectangle) {
+	sx := sliceEdges(src.Min.X, src.Max.X, insets.Left, insets.Right)
+	sy := sliceEdges(src.Min.Y, src.Max.Y, insets.Top, insets.Bottom)
+	dx := sliceEdges(dest.Min.X, dest.Max.X, insets.Left, insets.Right)
+	dy := sliceEdges(dest.Min.Y, dest.Max.Y, insets.Top, insets.Bottom)
+
+	for row := range 3 {
+		for col := range 3 {
+			i := row*3 + col
+			srcs[i] = image.Rect(sx[col], sy[row], sx[col+1], sy[row+1])
+			dsts[i] = image.Rect(dx[col], dy[row], dx[col+1], dy[row+1])
+		}
+	}
+	return srcs, dsts
+}
+
+// sliceEdges returns the 4 cell edges along one axis from lo to hi with the
+// given border sizes, shrinking the borders in proportion if they don't fit
+func sliceEdges(lo, hi, before, after int) [4]int {
+	size := hi - lo
+	if before+after > size {
+		if size <= 0 {
+			return [4]int{lo, lo, lo, lo}
+		}
+		before = size * before / (before + after)
+		after = size - before
+	}
+	return [4]int{lo, lo + before, hi - after, hi}
+}
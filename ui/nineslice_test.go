@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"image"
+	"testing"
+)
+
+func TestNineSliceRects(t *testing.T) {
+	src := image.Rect(0, 0, 32, 16)
+	insets := Insets{Left: 4, Top: 6, Right: 8, Bottom: 2}
+
+	tests := []struct {
+		name     string
+		dest     image.Rectangle
+		wantSrcs map[int]image.Rectangle // By cell, row by row from the top left
+		wantDsts map[int]image.Rectangle
+	}{
+		{
+			name: "corners unscaled",
+			dest: image.Rect(10, 20, 110, 60),
+			wantSrcs: map[int]image.Rectangle{
+				0: image.Rect(0, 0, 4, 6), 4: image.Rect(4, 6, 24, 14), 8: image.Rect(24, 14, 32, 16),
+			},
+			wantDsts: map[int]image.Rectangle{
+				0: image.Rect(10, 20, 14, 26), 2: image.Rect(102, 20, 110, 26),
+				4: image.Rect(14, 26, 102, 58), 8: image.Rect(102, 58, 110, 60),
+			},
+		},
+		{
+			// 6x4 is smaller than the 12x8 of borders, which shrink in proportion
+			name: "corners shrunk",
+			dest: image.Rect(0, 0, 6, 4),
+			wantDsts: map[int]image.Rectangle{
+				0: image.Rect(0, 0, 2, 3), 4: image.Rect(2, 3, 2, 3), 8: image.Rect(2, 3, 6, 4),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcs, dsts := nineSliceRects(src, insets, tt.dest)
+			for i, want := range tt.wantSrcs {
+				if srcs[i] != want {
+					t.Errorf("src cell %d = %v, want %v", i, srcs[i], want)
+				}
+			}
+			for i, want := range tt.wantDsts {
+				if dsts[i] != want {
+					t.Errorf("dest cell %d = %v, want %v", i, dsts[i], want)
+				}
+			}
+
+			// The cells cover dest exactly, without gaps or overlaps
+			var covered image.Rectangle
+			area := 0
+			for _, d := range dsts {
+				if d.Empty() {
+					continue
+				}
+				covered = covered.Union(d)
+				area += d.Dx() * d.Dy()
+			}
+			if covered != tt.dest || area != tt.dest.Dx()*tt.dest.Dy() {
+				t.Errorf("cells cover %v with area %d, want exactly %v", covered, area, tt.dest)
+			}
+		})
+	}
+
+	_, dsts := nineSliceRects(src, insets, image.Rect(5, 5, 5, 5))
+	for i, d := range dsts {
+		if !d.Empty() {
+			t.Errorf("empty dest gives non-empty cell %d %v", i, d)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"math"
+	"unicode/utf8"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/samredway/ebx/assetmgr"
+	"github.com/samredway/ebx/geom"
+)
+
+// Scroller scrolls a block of centred lines of text up through the viewport,
+// e.g. end credits or an intro crawl. The block starts just below the bottom
+// of the view and is done once its last line has left the top, unless Loop is
+// set. Call Update and Draw from a Scene and move on when Done
+//
+// Example:
+//
+//	// In Update
+//	s.credits.Speed = 40
+//	if ebiten.IsKeyPressed(ebiten.KeySpace) {
+//		s.credits.Speed = 160
+//	}
+//	s.credits.Update(dt)
+//	if s.credits.Done() {
+//		return NewTitleScene(), nil
+//	}
+type Scroller struct {
+	Lines []string
+	Font  *assetmgr.BitmapFont
+	Scale float64 // Glyph scale, 1 = glyph size
+	Speed float64 // px per second, can be changed while scrolling
+	Loop  bool    // Start again from the bottom rather than finishing
+
+	view   geom.Size
+	offset float64 // px scrolled up from the starting position
+}
+
+// SetViewport sets the size of the area scrolled through, e.g. from the
+// Scene's SetViewport
+func (s *Scroller) SetViewport(view geom.Size) { s.view = view }
+
+// Offset returns how far px the block has scrolled up
+func (s *Scroller) Offset() float64 { return s.offset }
+
+// Done reports whether the last line has scrolled off the top. Never true
+// when Loop is set
+func (s *Scroller) Done() bool {
+	return !s.Loop && s.offset >= s.distance()
+}
+
+// Update scrolls by Speed*dt
+func (s *Scroller) Update(dt float64) {
+	if s.Done() {
+		return
+	}
+	s.offset += s.Speed * dt
+	if s.Loop && s.distance() > 0 {
+		s.offset = math.Mod(s.offset, s.distance())
+	}
+}
+
+// Draw draws the visible lines, each centred horizontally in the viewport
+func (s *Scroller) Draw(screen *ebiten.Image) {
+	glyphW, glyphH := s.Font.GlyphSize()
+	lineH := float64(glyphH) * s.Scale
+	origin := screen.Bounds().Min
+	top := float64(s.view.H) - s.offset
+
+	for i, line := range s.Lines {
+		y := top + float64(i)*lineH
+		if y+lineH <= 0 {
+			continue
+		}
+		if y >= float64(s.view.H) {
+			break
+		}
+		w := float64(utf8.RuneCountInString(line)*glyphW) * s.Scale
+		x := (float64(s.view.W) - w) / 2
+		DrawBitmapString(screen, s.Font, line, float64(origin.X)+x, float64(origin.Y)+y, s.Scale)
+	}
+}
+
+// distance is how far the block scrolls from entering at the bottom of the
+// view to leaving at the top
+func (s *Scroller) distance() float64 {
+	_, glyphH := s.Font.GlyphSize()
+	return float64(s.view.H) + float64(len(s.Lines)*glyphH)*s.Scale
+}
+
+// NewScroller is constructor for Scroller, scrolling lines drawn in font at
+// speed px per second through a viewport of size view
+func NewScroller(font *assetmgr.BitmapFont, lines []string, speed float64, view geom.Size) *Scroller {
+	return &Scroller{
+		Lines: lines,
+		Font:  font,
+		Scale: 1,
+		Speed: speed,
+		view:  view,
+	}
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/samredway/ebx/geom"
+)
+
+func TestScroller(t *testing.T) {
+	font := testFont(t)
+	lines := []string{"CAB", "", "BAD"}
+	view := geom.Size{W: 96, H: 64}
+	// From entering at the bottom to leaving the top is the 64px view plus the
+	// 3 lines of 8px
+	const distance = 64 + 3*8
+
+	s := NewScroller(font, lines, 40, view)
+	s.Update(0.5)
+	if got := s.Offset(); got != 20 {
+		t.Errorf("Offset %v after 0.5s at 40px/s, want 20", got)
+	}
+	s.Speed = 80
+	s.Update(0.25)
+	if got := s.Offset(); got != 40 {
+		t.Errorf("Offset %v after speeding up, want 40", got)
+	}
+	s.Draw(ebiten.NewImage(view.W, view.H))
+
+	for range 10 {
+		if s.Done() {
+			break
+		}
+		s.Update(0.25)
+	}
+	if !s.Done() || s.Offset() < distance {
+		t.Fatalf("Done %v at offset %v, want done once scrolled %v", s.Done(), s.Offset(), distance)
+	}
+	at := s.Offset()
+	s.Update(1)
+	if s.Offset() != at {
+		t.Errorf("Offset moved from %v to %v after Done", at, s.Offset())
+	}
+
+	loop := NewScroller(font, lines, 40, view)
+	loop.Loop = true
+	for range 5 {
+		loop.Update(0.5)
+	}
+	if loop.Done() {
+		t.Error("looping scroller is Done")
+	}
+	if want := math.Mod(5*20, distance); loop.Offset() != want {
+		t.Errorf("looping Offset %v after scrolling 100px, want wrapped to %v", loop.Offset(), want)
+	}
+}
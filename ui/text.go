@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/samredway/ebx/assetmgr"
+)
+
+// DrawBitmapString draws s on screen with its top left corner at x, y using a
+// fixed width bitmap font, scaled by scale (1 = glyph size). Newlines start a
+// new line and characters missing from the font are drawn as an outlined box.
+// A space is left blank unless the font has its own glyph for it
+func DrawBitmapString(screen *ebiten.Image, font *assetmgr.BitmapFont, s string, x, y, scale float64) {
+	glyphW, glyphH := font.GlyphSize()
+	advX := float64(glyphW) * scale
+	advY := float64(glyphH) * scale
+
+	opts := &ebiten.DrawImageOptions{}
+	eachGlyph(font, s, x, y, scale, func(r rune, glyph *ebiten.Image, penX, penY float64) {
+		switch {
+		case glyph != nil:
+			opts.GeoM.Reset()
+			opts.GeoM.Scale(scale, scale)
+			opts.GeoM.Translate(penX, penY)
+			screen.DrawImage(glyph, opts)
+		case r != ' ':
+			drawMissingGlyph(screen, penX, penY, advX, advY, scale)
+		}
+	})
+}
+
+// eachGlyph lays s out fixed width from x, y and calls fn with each character
+// other than newlines, its glyph (nil if the font has none) and its top left
+func eachGlyph(font *assetmgr.BitmapFont, s string, x, y, scale float64, fn func(r rune, glyph *ebiten.Image, penX, penY float64)) {
+	glyphW, glyphH := font.GlyphSize()
+	advX := float64(glyphW) * scale
+	advY := float64(glyphH) * scale
+
+	penX, penY := x, y
+	for _, r := range s {
+		if r == '\n' {
+			penX = x
+			penY += advY
+			continue
+		}
+		glyph, _ := font.Glyph(r)
+		fn(r, glyph, penX, penY)
+		penX += advX
+	}
+}
+
+// drawMissingGlyph outlines a glyph cell so missing characters are obvious
+func drawMissingGlyph(screen *ebiten.Image, x, y, w, h, scale float64) {
+	inset := scale
+	vector.StrokeRect(
+		screen,
+		float32(x+inset), float32(y+inset),
+		float32(w-2*inset), float32(h-2*inset),
+		float32(scale), color.White, false,
+	)
+}
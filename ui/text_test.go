@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"image"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/samredway/ebx/assetmgr"
+	"github.com/samredway/ebx/internal/testutil"
+)
+
+// testFont is an 8x8 px font of " ABCDEFG" cut from 4 glyphs across by 2 down
+func testFont(t *testing.T) *assetmgr.BitmapFont {
+	t.Helper()
+	fsys := fstest.MapFS{"font.png": {Data: testutil.PNG(32, 16, 8, 8)}}
+	assets := assetmgr.NewAssets()
+	if err := assets.LoadBitmapFontFromFS(fsys, "font", "font.png", 8, 8, " ABCDEFG"); err != nil {
+		t.Fatal(err)
+	}
+	font, err := assets.GetBitmapFont("font")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return font
+}
+
+func TestBitmapStringGlyphs(t *testing.T) {
+	font := testFont(t)
+
+	type placed struct {
+		r     rune
+		glyph image.Rectangle // Of the font image, empty if missing
+		penX  float64
+		penY  float64
+	}
+	var got []placed
+	eachGlyph(font, "BAD\nE Z", 10, 20, 2, func(r rune, glyph *ebiten.Image, penX, penY float64) {
+		p := placed{r: r, penX: penX, penY: penY}
+		if glyph != nil {
+			p.glyph = glyph.Bounds()
+		}
+		got = append(got, p)
+	})
+
+	// Glyph i of the charset is tile i of the image, read across then down
+	want := []placed{
+		{'B', image.Rect(16, 0, 24, 8), 10, 20},
+		{'A', image.Rect(8, 0, 16, 8), 26, 20},
+		{'D', image.Rect(0, 8, 8, 16), 42, 20},
+		{'E', image.Rect(8, 8, 16, 16), 10, 36},
+		{' ', image.Rect(0, 0, 8, 8), 26, 36},
+		{'Z', image.Rectangle{}, 42, 36},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("laid out %d glyphs %v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("glyph %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Missing glyphs get a box rather than a panic
+	DrawBitmapString(ebiten.NewImage(64, 64), font, "BAD\nE Z", 10, 20, 2)
+}